@@ -5,25 +5,35 @@ package dex // import "basal.io/x/dex"
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
 	"crypto/tls"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
-	"log"
+	"math"
+	"math/rand"
 	"net/http"
 	"net/url"
 	"os"
 	"regexp"
+	"sort"
 	"strconv"
+	"sync"
 	"time"
 )
 
 const (
 	applicationId = "d89443d2-327c-4a6f-89e5-496bbb0317db"
 	agent         = "Dexcom Share/3.0.2.11 CFNetwork/711.2.23 Darwin/14.0.0"
-	loginUrl      = "https://share1.dexcom.com/ShareWebServices/Services/General/LoginPublisherAccountByName"
-	queryUrl      = "https://share1.dexcom.com/ShareWebServices/Services/Publisher/ReadPublisherLatestGlucoseValues"
+	usHost        = "share1.dexcom.com"
+	ousHost       = "shareous1.dexcom.com"
+	loginPath     = "/ShareWebServices/Services/General/LoginPublisherAccountByName"
+	queryPath     = "/ShareWebServices/Services/Publisher/ReadPublisherLatestGlucoseValues"
 )
 
 // The type of blood glucose trend (direction).
@@ -89,6 +99,31 @@ func (d Dir) Emoji() string {
 	}
 }
 
+// Degrees returns the angle of the trend arrow, in degrees
+// counterclockwise from the horizontal, following Nightscout:
+// Flat is 0, DoubleUp 90 and DoubleDown -90. Directions without
+// an arrow return NaN.
+func (d Dir) Degrees() float64 {
+	switch d {
+	case DoubleUp:
+		return 90
+	case SingleUp:
+		return 60
+	case FortyFiveUp:
+		return 45
+	case Flat:
+		return 0
+	case FortyFiveDown:
+		return -45
+	case SingleDown:
+		return -60
+	case DoubleDown:
+		return -90
+	default:
+		return math.NaN()
+	}
+}
+
 var numToDir = map[int]Dir{
 	0: None,
 	1: DoubleUp,
@@ -102,17 +137,65 @@ var numToDir = map[int]Dir{
 	9: RateOutOfRange,
 }
 
-var client = http.Client{Transport: &http.Transport{
-	TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-}}
+var client = http.Client{Transport: newTransport()}
+
+func newTransport() *http.Transport {
+	return &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}
+}
 
 var datePat = regexp.MustCompile(".*\\(([^)]+)\\).*")
 
+// Dexcom session tokens are GUIDs.
+var tokenPat = regexp.MustCompile("^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$")
+
+const nilToken = "00000000-0000-0000-0000-000000000000"
+
+// The number of entries a Tail returns at most, by default: over a
+// month of readings.
+const defaultMaxEntries = 10000
+
 type Session struct {
-	token string
-	path  string
-	user  string
-	pass  string
+	dropped uint64 // Accessed atomically; keep 64-bit aligned.
+
+	mu         sync.Mutex // Protects token, refreshing and host.
+	token      string
+	refreshing *flight
+	host       string // The Share host in use; "" means usHost.
+
+	path string
+	user string
+	pass string
+
+	overflow      Overflow
+	maxAge        time.Duration
+	maxBackfill   time.Duration
+	jitter        time.Duration
+	jitterSrc     rand.Source
+	validate      bool
+	auth          Authenticator
+	order         Order
+	dropInvalid   bool
+	maxEntries    int
+	failover      bool
+	stateKey      []byte
+	penaltyCap    time.Duration
+	penaltyStep   time.Duration
+	emitLatest    bool
+	noDrain       bool
+	reconnectHook func(attempt int, wait time.Duration)
+	responseHook  func(status int, body []byte)
+	logger        Logger
+	statePath     string
+
+	// After, if set, replaces time.After in the waits of Stream and
+	// StreamRetry, for tests.
+	after func(d time.Duration) <-chan time.Time
+
+	client          *http.Client
+	idleConnTimeout time.Duration
+	proxy           *url.URL
 }
 
 type Entry struct {
@@ -120,30 +203,77 @@ type Entry struct {
 	Value int       // The current blood glucose level in mg/dL
 	Dir   Dir       // The direction of blood glucose trending.
 	Raw   string    // The raw JSON entry in string form.
+
+	// Skew is the server time of the entry less its walltime.
+	// A large skew suggests the device's clock is wrong.
+	Skew time.Duration
+
+	// Noise is the noise level Dexcom reports for the reading, from
+	// 1 (clean) to 4 (heavy), or 0 if it reports none.
+	Noise int
+
+	// Synthetic marks entries that are estimates, not readings, as
+	// made by Interpolate. Entries from Dexcom are never synthetic.
+	Synthetic bool
+
+	// Seq numbers the entries emitted by a Stream, from 1, so that
+	// consumers may detect entries lost or reordered downstream.
+	// Sequence numbers are per stream, not global; they are 0 for
+	// entries not from a Stream. Entries dropped by the overflow
+	// policy leave gaps.
+	Seq uint64
 }
 
 type savedSession struct {
 	Token string `json:"token"`
+	MAC   string `json:"mac,omitempty"` // Set if the session has a state key.
 }
 
-func restore(path string) *Session {
+// The MAC of token under the session's state key.
+func (s *Session) mac(token string) string {
+	h := hmac.New(sha256.New, s.stateKey)
+	h.Write([]byte(token))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Restore the token saved at path, returning "" if there is none.
+// A corrupt file is set aside, as path.corrupt, so that a fresh one
+// may be saved.
+func (s *Session) restore(path string) string {
+	if path == "" {
+		return ""
+	}
+
 	file, err := os.Open(path)
 	if err != nil {
-		return nil
+		return ""
 	}
 	defer file.Close()
 	r := bufio.NewReader(file)
 	d := json.NewDecoder(r)
 
 	var saved savedSession
-	if err := d.Decode(&saved); err != nil {
-		return nil
+	err = d.Decode(&saved)
+	if err == nil && s.stateKey != nil &&
+		!hmac.Equal([]byte(saved.MAC), []byte(s.mac(saved.Token))) {
+		err = errors.New("MAC mismatch")
+	}
+	if err != nil {
+		s.logf("Corrupt session file %v: %v\n", path, err)
+		if err := os.Rename(path, path+".corrupt"); err != nil {
+			s.logf("Failed to set aside session file: %v\n", err)
+		}
+		return ""
 	}
 
-	return &Session{token: saved.Token, path: path}
+	return saved.Token
 }
 
 func (s *Session) save() error {
+	if s.path == "" {
+		return nil
+	}
+
 	file, err := os.Create(s.path)
 	if err != nil {
 		return err
@@ -154,191 +284,420 @@ func (s *Session) save() error {
 	defer w.Flush()
 
 	enc := json.NewEncoder(w)
-	if err := enc.Encode(savedSession{Token: s.token}); err != nil {
+	saved := savedSession{Token: s.getToken()}
+	if s.stateKey != nil {
+		saved.MAC = s.mac(saved.Token)
+	}
+	if err := enc.Encode(saved); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// The file in which Dial saves the session of user by default. The
+// name is derived from a digest of user, which may contain
+// characters unfit for file names, such as the slashes of an email
+// address, and may differ from another only in case.
+func sessionPath(user string) string {
+	sum := sha256.Sum256([]byte(user))
+	return os.ExpandEnv("$HOME/.dex.") + hex.EncodeToString(sum[:8])
+}
+
+// The file in which Dial used to save the session of user.
+func legacySessionPath(user string) string {
+	return os.ExpandEnv("$HOME/.dex.") + user
+}
+
 // Begin a new session with the given Dexcom username and password.
-// Dial will save and restore session tokens in file $HOME/.dex.$user.
-func Dial(user, pass string) (*Session, error) {
-	path := os.ExpandEnv("$HOME/.dex.") + user
-	s := restore(path)
-	if s != nil {
-		//		log.Printf("restored saved session from %v\n", path)
-		s.user = user
-		s.pass = pass
-		return s, nil
-	}
-
-	s = &Session{path: path, user: user, pass: pass}
-	if err := s.login(); err != nil {
+// Dial will save and restore session tokens in file $HOME/.dex.$hash,
+// where hash is a digest of user, unless given another
+// WithSessionPath. A token saved by older versions, in
+// $HOME/.dex.$user, is moved to the new file.
+func Dial(user, pass string, opts ...Option) (*Session, error) {
+	s := &Session{path: sessionPath(user), user: user, pass: pass}
+	s.apply(opts)
+
+	token := s.restore(s.path)
+	if token == "" && s.path == sessionPath(user) {
+		legacy := legacySessionPath(user)
+		if token = s.restore(legacy); token != "" {
+			s.token = token
+			if err := s.save(); err != nil {
+				s.logf("Failed to save session: %v\n", err)
+			} else {
+				os.Remove(legacy)
+			}
+		}
+	}
+	if token != "" {
+		//		log.Printf("restored saved session from %v\n", s.path)
+		s.token = token
+		if !s.validate {
+			return s, nil
+		}
+		err := s.Ping(context.Background())
+		if err == nil {
+			return s, nil
+		}
+		s.logf("Restored session is not valid: %v\n", err)
+	}
+
+	if err := s.login(context.Background()); err != nil {
 		return nil, err
 	}
 
 	if err := s.save(); err != nil {
-		log.Printf("ailed to save session: %v\n", err)
+		s.logf("Failed to save session: %v\n", err)
 	}
 
 	return s, nil
 }
 
-func (s *Session) refresh() error {
-	if err := s.login(); err != nil {
-		return err
+// DialWithToken begins a session with a token obtained elsewhere,
+// without logging in. The token is neither saved nor restored.
+// Unless credentials are given WithCredentials, queries fail with
+// ErrNoCredentials once the token expires.
+func DialWithToken(token string, opts ...Option) (*Session, error) {
+	s := &Session{token: token}
+	s.apply(opts)
+	return s, nil
+}
+
+// Refresh logs in again, replacing and saving the session's token.
+// Concurrent calls share a single login.
+func (s *Session) Refresh() error {
+	return s.RefreshContext(context.Background())
+}
+
+// RefreshContext is like Refresh, but the login is bound to ctx.
+func (s *Session) RefreshContext(ctx context.Context) error {
+	s.mu.Lock()
+	if f := s.refreshing; f != nil {
+		s.mu.Unlock()
+		select {
+		case <-f.done:
+			return f.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
-	if err := s.save(); err != nil {
-		log.Printf("ailed to save session: %v\n", err)
+	f := &flight{done: make(chan struct{})}
+	s.refreshing = f
+	s.mu.Unlock()
+
+	f.err = s.login(ctx)
+	if f.err == nil {
+		if err := s.save(); err != nil {
+			s.logf("Failed to save session: %v\n", err)
+		}
 	}
-	return nil
+
+	s.mu.Lock()
+	s.refreshing = nil
+	s.mu.Unlock()
+	close(f.done)
+
+	return f.err
+}
+
+// A flight is an in-progress refresh.
+type flight struct {
+	done chan struct{}
+	err  error
+}
+
+func (s *Session) getToken() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.token
+}
+
+func (s *Session) getHost() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.host == "" {
+		return usHost
+	}
+	return s.host
+}
+
+func (s *Session) setHost(host string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.host = host
+}
+
+func (s *Session) setToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = token
 }
 
 type entryJson struct {
 	WT    string `json:"WT"`
-	Trend int    `json:"Trend"`
-	Value int    `json:"Value"`
+	ST    string `json:"ST"`
+	Trend trend  `json:"Trend"`
+	Value mgdl   `json:"Value"`
+	Noise int    `json:"Noise"`
+}
+
+// A trend is a Dir as encoded by Dexcom: either a number, or,
+// in newer responses, a name such as "FortyFiveUp".
+type trend Dir
+
+func (t *trend) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		var name string
+		if err := json.Unmarshal(b, &name); err != nil {
+			return err
+		}
+		d, err := ParseDir(name)
+		if err != nil {
+			return err
+		}
+		*t = trend(d)
+		return nil
+	}
+
+	var num int
+	if err := json.Unmarshal(b, &num); err != nil {
+		return err
+	}
+	*t = trend(numToDir[num])
+	return nil
 }
 
 // Retrieve entries since time begin. This is best effort. The underlying
 // data may not be available from Dexcom, nor is it guaranteed to be
 // complete.
 func (s *Session) Tail(howlong time.Duration) ([]Entry, error) {
+	return s.TailContext(context.Background(), howlong)
+}
+
+// TailContext is like Tail, but the request is bound to ctx. If ctx
+// is done before the response has been read, TailContext returns the
+// entries read so far, if any, with ctx.Err(): callers with deadlines
+// should use the entries even when the error is not nil.
+func (s *Session) TailContext(ctx context.Context, howlong time.Duration) ([]Entry, error) {
+	entries, err := s.tail(ctx, howlong, nil, true)
+	return s.order.apply(entries), err
+}
+
+// TailWithMeta is like Tail, but also returns metadata from Dexcom's
+// response.
+func (s *Session) TailWithMeta(howlong time.Duration) ([]Entry, TailMeta, error) {
+	var meta TailMeta
+	entries, err := s.tail(context.Background(), howlong, &meta, true)
+	return s.order.apply(entries), meta, err
+}
+
+// Tail entries, oldest first, recording metadata in meta if it is
+// not nil. Unless
+// retry is set, error statuses are returned rather than retried, and
+// the token is never refreshed.
+func (s *Session) tail(ctx context.Context, howlong time.Duration, meta *TailMeta, retry bool) ([]Entry, error) {
 	var resp *http.Response
+	tries := 0
 
 	for {
 		minutes := howlong.Minutes()
 		count := int(minutes) / 5
+		if count > s.entryLimit() {
+			count = s.entryLimit()
+		}
 		params := url.Values{
-			"sessionID": {s.token},
+			"sessionID": {s.getToken()},
 			"minutes":   {fmt.Sprintf("%.0f", minutes)},
 			"maxCount":  {fmt.Sprintf("%d", count)}}
 
-		req, err := http.NewRequest("POST", queryUrl+"?"+params.Encode(), nil)
+		req, err := http.NewRequest("POST", "https://"+s.getHost()+queryPath+"?"+params.Encode(), nil)
 		if err != nil {
 			return nil, err
 		}
+		req = req.WithContext(ctx)
 		addHeaders(req)
-		req.Header.Add("content-length", "0") // necessary?
-
-		tries := 0
 
-		resp, err = client.Do(req)
+		resp, err = s.httpClient().Do(req)
 		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
 			return nil, err
 		}
+		s.hook(resp)
 
 		if resp.StatusCode < 400 {
 			break
 		}
+		serr := newStatusError(resp)
+		closeBody(resp)
 
-		// Assume token is expired.
-		// log.Printf("refreshing token\n")
-		if err := s.refresh(); err != nil {
-			return nil, err
+		if tries++; tries == 5 || !retry {
+			return nil, serr
 		}
 
-		// Just back off, and wait for eventual success?
-		if tries++; tries == 5 {
-			return nil, errors.New(
-				fmt.Sprintf("Giving up after %d tries", tries))
+		switch {
+		case serr.Auth():
+			// The token is expired or revoked. Dexcom reports this
+			// with 401 or 403, or with 500 and a session error code.
+			if err := s.RefreshContext(ctx); err != nil {
+				s.event(RefreshEvent{Err: err.Error()})
+				return nil, err
+			}
+			s.event(RefreshEvent{})
+		case serr.Temporary():
+			// Dexcom is having trouble; keep the token and back off.
+			wait := time.Duration(tries) * time.Second
+			s.event(BackoffEvent{Attempt: tries, Wait: wait, Reason: serr.Error()})
+			if !sleep(ctx, wait) {
+				return nil, ctx.Err()
+			}
+		default:
+			return nil, serr
 		}
 	}
-
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	defer closeBody(resp)
+	if meta != nil {
+		*meta = newTailMeta(resp, tries+1)
 	}
 
-	// var pp bytes.Buffer
-	// json.Indent(&pp, body, "", "	")
-	// log.Printf("body \"%s\"", string(pp.Bytes()))
-
-	var ejs []entryJson
-	if err := json.Unmarshal(body, &ejs); err != nil {
-		return nil, errors.New(
-			fmt.Sprintf("Failed to unmarshal \"%v\": %v", string(body), err))
+	// Decode entries one at a time, so that a truncated or
+	// otherwise malformed response still yields its valid prefix.
+	dec := json.NewDecoder(resp.Body)
+	if tok, err := dec.Token(); err != nil {
+		return nil, err
+	} else if tok != json.Delim('[') {
+		return nil, errors.New(fmt.Sprintf("Expected array, got %v", tok))
 	}
 
-	entries := make([]Entry, len(ejs))
+	// Parse at most twice the maximum number of entries before
+	// discarding the oldest, bounding the memory a response can use.
+	limit := s.entryLimit()
+	var (
+		entries   []Entry
+		truncated bool
+	)
+	for dec.More() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			if ctx.Err() != nil {
+				// Out of time: return what we have.
+				break
+			}
+			s.logf("Dropping malformed remainder of response: %v\n", err)
+			break
+		}
 
-	for i, ej := range ejs {
-		matches := datePat.FindStringSubmatch(ej.WT)
-		if matches == nil || len(matches) != 2 {
-			return nil, errors.New(fmt.Sprintf("No match for date in %v", ej.WT))
+		var ej entryJson
+		if err := json.Unmarshal(raw, &ej); err != nil {
+			s.logf("Skipping entry %s: %v\n", raw, err)
+			continue
 		}
 
-		msecs, err := strconv.ParseInt(matches[1], 10, 64)
+		wt, err := parseDate(ej.WT)
 		if err != nil {
-			return nil, err
+			s.logf("Skipping entry %s: %v\n", raw, err)
+			continue
+		}
+
+		e := Entry{
+			Time:  wt,
+			Value: int(ej.Value),
+			Dir:   Dir(ej.Trend),
+			Raw:   string(raw),
+			Noise: ej.Noise,
+		}
+		if st, err := parseDate(ej.ST); err == nil {
+			e.Skew = st.Sub(wt)
+		}
+		if s.maxAge > 0 && time.Since(e.Time) > s.maxAge {
+			continue
+		}
+		if s.dropInvalid {
+			if err := e.Valid(); err != nil {
+				s.logf("Skipping entry %s: %v\n", raw, err)
+				continue
+			}
+		}
+		entries = append(entries, e)
+		if len(entries) >= 2*limit {
+			entries = latestEntries(entries, limit)
+			truncated = true
 		}
+	}
 
-		j := len(entries) - i - 1
-		entries[j].Value = ej.Value
-		entries[j].Time = time.Unix(msecs/1000, 0)
-		entries[j].Dir = numToDir[ej.Trend]
+	if len(entries) > limit {
+		entries = latestEntries(entries, limit)
+		truncated = true
 	}
+	if truncated {
+		s.logf("Truncated response to the latest %d entries\n", limit)
+	}
+	// Dexcom returns entries newest first, but is not to be trusted.
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.Before(entries[j].Time)
+	})
 
-	return entries, nil
+	return entries, ctx.Err()
 }
 
-// Stream entries as they become available. They are written
-// to channel out; the channel is closed on error.
-func (s *Session) Stream(begin time.Time, out chan<- Entry) {
-	// TODO: report skew
-	// TODO: base eta on "now" time instead of begin (?),
-	// or compute skew based on the difference between
-	// this time and wall time?
-	//
-	// Higher backoff value?
-
-	defer close(out)
+// The number of entries a Tail returns at most.
+func (s *Session) entryLimit() int {
+	if s.maxEntries > 0 {
+		return s.maxEntries
+	}
+	return defaultMaxEntries
+}
 
-	eta := time.Now()
-	penalty := 0 * time.Second
-	total := 0 * time.Second
+// LatestEntries returns the latest n of entries, oldest first.
+func latestEntries(entries []Entry, n int) []Entry {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.Before(entries[j].Time)
+	})
+	return append([]Entry(nil), entries[len(entries)-n:]...)
+}
 
-	for {
-		now := time.Now()
-		if eta.After(now) {
-			wait := eta.Sub(now)
-			time.Sleep(wait)
-		}
-		time.Sleep(penalty)
-		total += penalty
+// Ping checks that the session can query Dexcom with its current
+// token, returning a *StatusError if Dexcom refuses. Ping neither
+// retries nor refreshes the token.
+func (s *Session) Ping(ctx context.Context) error {
+	_, err := s.tail(ctx, 10*time.Minute, nil, false)
+	return err
+}
 
-		if penalty < 10*time.Second {
-			penalty += time.Second
-		}
+// Parse a Dexcom date of the form "/Date(1462404576000)/".
+func parseDate(date string) (time.Time, error) {
+	matches := datePat.FindStringSubmatch(date)
+	if matches == nil || len(matches) != 2 {
+		return time.Time{}, errors.New(fmt.Sprintf("No match for date in %v", date))
+	}
 
-		// We extend our duration a little bit to give some wiggle
-		// room for uneven sampling.
-		dur := time.Since(begin) + 5*time.Minute
-		ents, err := s.Tail(dur)
-		if err != nil {
-			log.Printf("Failed to retrieve data\n")
-			return
-		}
+	msecs, err := strconv.ParseInt(matches[1], 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
 
-		var newest *Entry
-		for i := range ents {
-			if ents[i].Time.After(begin) {
-				out <- ents[i]
-				newest = &ents[i]
-			}
-		}
+	return time.Unix(msecs/1000, (msecs%1000)*int64(time.Millisecond)), nil
+}
 
-		if newest != nil {
-			// Dexcom samples every five minutes. Of course some may be
-			// missed because devices are offline, or other failures.
-			log.Printf("Sampled with penalty %v\n", total)
-			begin = newest.Time
-			eta = begin.Add(5 * time.Minute)
-			penalty = 0 * time.Second
-			total = 0 * time.Second
-		}
+// Pass resp to the session's response hook, if any, leaving its
+// body to be read again.
+func (s *Session) hook(resp *http.Response) {
+	if s.responseHook == nil {
+		return
 	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	s.responseHook(resp.StatusCode, body)
+}
+
+// Close the response body, first reading what remains of it so that
+// the connection may be reused.
+func closeBody(resp *http.Response) {
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
 }
 
 func addHeaders(req *http.Request) {
@@ -353,31 +712,97 @@ type loginBody struct {
 	ApplicationId string `json:"applicationId"`
 }
 
-func (s *Session) login() error {
+func (s *Session) login(ctx context.Context) error {
+	auth := s.auth
+	if auth == nil {
+		auth = publisherAuth{s}
+	}
+
+	token, err := auth.Authenticate(ctx)
+	if err != nil {
+		return err
+	}
+	s.setToken(token)
+	return nil
+}
+
+// A publisherAuth logs in to a publisher account by name, with the
+// session's username and password. It is the default Authenticator.
+type publisherAuth struct {
+	s *Session
+}
+
+// Authenticate at the session's host, failing over to the other
+// region's if the session was dialed WithRegionFailover. The session
+// stays with the host that succeeded.
+func (a publisherAuth) Authenticate(ctx context.Context) (string, error) {
+	s := a.s
+	if s.user == "" && s.pass == "" {
+		return "", ErrNoCredentials
+	}
+
+	host := s.getHost()
+	token, err := a.login(ctx, host)
+	if err == nil || !s.failover || ctx.Err() != nil {
+		return token, err
+	}
+
+	other := ousHost
+	if host == ousHost {
+		other = usHost
+	}
+	s.logf("Login at %s failed: %v; trying %s\n", host, err, other)
+	if token, oerr := a.login(ctx, other); oerr == nil {
+		s.setHost(other)
+		return token, nil
+	}
+	return "", err
+}
+
+func (a publisherAuth) login(ctx context.Context, host string) (string, error) {
+	s := a.s
+
 	body := loginBody{
 		User:          s.user,
 		Password:      s.pass,
 		ApplicationId: applicationId}
 	bodyJson, err := json.Marshal(body)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	req, err := http.NewRequest("POST", loginUrl, bytes.NewReader(bodyJson))
+	req, err := http.NewRequest("POST", "https://"+host+loginPath, bytes.NewReader(bodyJson))
 	if err != nil {
-		return err
+		return "", err
 	}
+	req = req.WithContext(ctx)
 	addHeaders(req)
 
-	resp, err := client.Do(req)
+	resp, err := s.httpClient().Do(req)
 	if err != nil {
-		return err
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", err
+	}
+	s.hook(resp)
+	defer closeBody(resp)
+	if resp.StatusCode >= 400 {
+		return "", newStatusError(resp)
 	}
 
 	bytes, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	return json.Unmarshal(bytes, &s.token)
+	var token string
+	if err := json.Unmarshal(bytes, &token); err != nil {
+		return "", err
+	}
+	// Dexcom may accept a login yet return an empty or nil token.
+	if !tokenPat.MatchString(token) || token == nilToken {
+		return "", ErrAuth
+	}
+	return token, nil
 }
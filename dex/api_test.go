@@ -0,0 +1,521 @@
+package dex
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A fakeDexcom serves Dexcom's Share API to sessions in tests,
+// without the network. It is the session's HTTP transport.
+type fakeDexcom struct {
+	login http.HandlerFunc // Handles logins; nil issues a new token.
+	query http.HandlerFunc // Handles queries; nil returns no entries.
+
+	mu       sync.Mutex
+	logins   int
+	sessions []string // The session ID of each query, in order.
+	hosts    []string // The host of each request, in order.
+}
+
+func (f *fakeDexcom) RoundTrip(r *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	f.ServeHTTP(rec, r)
+	if err := r.Context().Err(); err != nil {
+		return nil, err
+	}
+	return rec.Result(), nil
+}
+
+func (f *fakeDexcom) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.hosts = append(f.hosts, r.URL.Host)
+	f.mu.Unlock()
+
+	switch r.URL.Path {
+	case loginPath:
+		f.mu.Lock()
+		f.logins++
+		n := f.logins
+		f.mu.Unlock()
+		if f.login != nil {
+			f.login(w, r)
+			return
+		}
+		fmt.Fprintf(w, "%q", testToken(n))
+	case queryPath:
+		f.mu.Lock()
+		f.sessions = append(f.sessions, r.URL.Query().Get("sessionID"))
+		f.mu.Unlock()
+		if f.query != nil {
+			f.query(w, r)
+			return
+		}
+		fmt.Fprint(w, "[]")
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeDexcom) client() *http.Client {
+	return &http.Client{Transport: f}
+}
+
+// A transport that connects every request to srv, a TLS server
+// standing in for Dexcom.
+func serverTransport(srv *httptest.Server) *http.Transport {
+	t := newTransport()
+	t.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, network, srv.Listener.Addr().String())
+	}
+	return t
+}
+
+// Dial a session with token, using f.
+func (f *fakeDexcom) dial(t testing.TB, token string, opts ...Option) (*Session, *testLog) {
+	t.Helper()
+	log := &testLog{}
+	opts = append([]Option{
+		WithCredentials("user", "pass"),
+		WithHTTPClient(f.client()),
+		WithLogger(log),
+	}, opts...)
+	s, err := DialWithToken(token, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, log
+}
+
+func (f *fakeDexcom) counts() (logins, queries int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.logins, len(f.sessions)
+}
+
+// The nth token issued by a fakeDexcom.
+func testToken(n int) string {
+	return fmt.Sprintf("%08d-0000-0000-0000-000000000000", n)
+}
+
+// A testLog is a Logger that records messages.
+type testLog struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *testLog) Printf(format string, v ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func (l *testLog) contains(substr string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, line := range l.lines {
+		if strings.Contains(line, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Encode entries as Dexcom does, newest first.
+func dexcomJSON(entries ...Entry) string {
+	strs := make([]string, len(entries))
+	for i, e := range entries {
+		ms := e.Time.UnixNano() / int64(time.Millisecond)
+		strs[len(strs)-1-i] = fmt.Sprintf(
+			`{"WT":"Date(%d)","ST":"Date(%d)","DT":"Date(%d-0500)","Value":%d,"Trend":%q}`,
+			ms, ms, ms, e.Value, e.Dir.String())
+	}
+	return "[" + strings.Join(strs, ",") + "]"
+}
+
+// Readings of values, five minutes apart, the last of them now.
+func readings(values ...int) []Entry {
+	now := time.Now().Truncate(time.Second)
+	entries := make([]Entry, len(values))
+	for i, v := range values {
+		entries[i] = Entry{
+			Time:  now.Add(time.Duration(i-len(values)+1) * 5 * time.Minute),
+			Value: v,
+			Dir:   Flat,
+		}
+	}
+	return entries
+}
+
+// Serve entries to every query.
+func serveEntries(entries []Entry) http.HandlerFunc {
+	body := dexcomJSON(entries...)
+	return func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, body)
+	}
+}
+
+func TestTailRefreshesExpiredSession(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{"401", http.StatusUnauthorized, ""},
+		{"403", http.StatusForbidden, ""},
+		{"SessionIdNotFound", http.StatusInternalServerError,
+			`{"Code":"SessionIdNotFound","Message":"Session ID not found"}`},
+		{"SessionNotValid", http.StatusInternalServerError,
+			`{"Code":"SessionNotValid","Message":"Session not active or timed out"}`},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			entries := readings(100, 110)
+			f := &fakeDexcom{}
+			f.query = func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Query().Get("sessionID") == "expired" {
+					w.WriteHeader(c.status)
+					fmt.Fprint(w, c.body)
+					return
+				}
+				serveEntries(entries)(w, r)
+			}
+			s, _ := f.dial(t, "expired")
+
+			got, err := s.Tail(time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 2 {
+				t.Errorf("got %d entries, want 2", len(got))
+			}
+			if logins, _ := f.counts(); logins != 1 {
+				t.Errorf("logged in %d times, want 1", logins)
+			}
+			if want := []string{"expired", testToken(1)}; strings.Join(f.sessions, " ") != strings.Join(want, " ") {
+				t.Errorf("queried with sessions %v, want %v", f.sessions, want)
+			}
+		})
+	}
+}
+
+func TestTailRetriesTemporaryErrorWithSameToken(t *testing.T) {
+	entries := readings(100)
+	f := &fakeDexcom{}
+	f.query = func(w http.ResponseWriter, r *http.Request) {
+		if _, queries := f.counts(); queries == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		serveEntries(entries)(w, r)
+	}
+	s, _ := f.dial(t, testToken(42))
+
+	got, err := s.TailContext(context.Background(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 {
+		t.Errorf("got %d entries, want 1", len(got))
+	}
+	logins, queries := f.counts()
+	if logins != 0 {
+		t.Errorf("logged in %d times, want 0", logins)
+	}
+	if queries != 2 {
+		t.Errorf("queried %d times, want 2", queries)
+	}
+	for _, id := range f.sessions {
+		if id != testToken(42) {
+			t.Errorf("queried with session %s, want %s", id, testToken(42))
+		}
+	}
+}
+
+func TestStatusErrorAuth(t *testing.T) {
+	for _, c := range []struct {
+		err        StatusError
+		auth, temp bool
+	}{
+		{StatusError{StatusCode: 401}, true, false},
+		{StatusError{StatusCode: 403}, true, false},
+		{StatusError{StatusCode: 404}, false, false},
+		{StatusError{StatusCode: 500}, false, true},
+		{StatusError{StatusCode: 503}, false, true},
+		{StatusError{StatusCode: 500, Code: "SessionIdNotFound"}, true, false},
+		{StatusError{StatusCode: 500, Code: "SessionNotValid"}, true, false},
+		{StatusError{StatusCode: 500, Code: "AccountPasswordInvalid"}, false, true},
+	} {
+		if got := c.err.Auth(); got != c.auth {
+			t.Errorf("%v: Auth() = %v, want %v", &c.err, got, c.auth)
+		}
+		if got := c.err.Temporary(); got != c.temp {
+			t.Errorf("%v: Temporary() = %v, want %v", &c.err, got, c.temp)
+		}
+	}
+}
+
+func TestTailKeepsValidPrefix(t *testing.T) {
+	entries := readings(100, 110, 120)
+	valid := dexcomJSON(entries...)
+	for _, suffix := range []string{
+		`,{"WT":"Date(16`,
+		`,garbage]`,
+		`,{"WT":"Date(1)","Value":}]`,
+	} {
+		f := &fakeDexcom{}
+		f.query = func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, strings.TrimSuffix(valid, "]")+suffix)
+		}
+		s, log := f.dial(t, testToken(1))
+
+		got, err := s.Tail(time.Hour)
+		if err != nil {
+			t.Fatalf("%s: %v", suffix, err)
+		}
+		if len(got) != 3 {
+			t.Errorf("%s: got %d entries, want 3", suffix, len(got))
+		}
+		if !log.contains("Dropping malformed remainder") {
+			t.Errorf("%s: malformed remainder not logged", suffix)
+		}
+	}
+}
+
+func TestRefreshSavesToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session")
+	f := &fakeDexcom{}
+	s, _ := f.dial(t, "old", WithSessionPath(path))
+
+	if err := s.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.getToken(); got != testToken(1) {
+		t.Errorf("token is %s, want %s", got, testToken(1))
+	}
+	if got := s.restore(path); got != testToken(1) {
+		t.Errorf("saved token is %q, want %s", got, testToken(1))
+	}
+}
+
+func TestRefreshSingleFlight(t *testing.T) {
+	release := make(chan struct{})
+	f := &fakeDexcom{}
+	f.login = func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		fmt.Fprintf(w, "%q", testToken(1))
+	}
+	s, _ := f.dial(t, "old")
+
+	const n = 10
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() { errs <- s.Refresh() }()
+	}
+	// Let the refreshes pile up behind the first login.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	for i := 0; i < n; i++ {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+	if logins, _ := f.counts(); logins != 1 {
+		t.Errorf("logged in %d times, want 1", logins)
+	}
+	if got := s.getToken(); got != testToken(1) {
+		t.Errorf("token is %s, want %s", got, testToken(1))
+	}
+}
+
+func TestTailSkipsMalformedDate(t *testing.T) {
+	entries := readings(100, 110)
+	valid := dexcomJSON(entries...)
+	bad := `{"WT":"Date(soon)","ST":"Date(1)","DT":"Date(1-0500)","Value":105,"Trend":"Flat"}`
+	f := &fakeDexcom{}
+	f.query = func(w http.ResponseWriter, r *http.Request) {
+		// Place the malformed entry between the valid ones.
+		fmt.Fprint(w, strings.Replace(valid, "},{", "},"+bad+",{", 1))
+	}
+	s, log := f.dial(t, testToken(1))
+
+	got, err := s.Tail(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d entries, want 2", len(got))
+	}
+	for i, e := range got {
+		if e.Value != entries[i].Value {
+			t.Errorf("entry %d has value %d, want %d", i, e.Value, entries[i].Value)
+		}
+	}
+	if !log.contains("Skipping entry") {
+		t.Error("malformed entry not logged")
+	}
+}
+
+func TestTailCancelsHangingLogin(t *testing.T) {
+	f := &fakeDexcom{}
+	f.login = func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}
+	f.query = func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}
+	s, _ := f.dial(t, "expired")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		_, err := s.TailContext(ctx, time.Hour)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != context.DeadlineExceeded {
+			t.Errorf("got error %v, want %v", err, context.DeadlineExceeded)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Tail hung in login")
+	}
+
+	// A stream, too, stops when cancelled during a login.
+	ctx, cancel = context.WithCancel(context.Background())
+	out := make(chan Entry)
+	go s.StreamContext(ctx, time.Now().Add(-time.Hour), out)
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	select {
+	case _, ok := <-out:
+		if ok {
+			t.Error("stream delivered an entry")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream hung in login")
+	}
+}
+
+func TestTailDeadlineReturnsPartialEntries(t *testing.T) {
+	entries := readings(100, 110, 120)
+	body := dexcomJSON(entries...)
+	f := &fakeDexcom{}
+	f.query = func(w http.ResponseWriter, r *http.Request) {
+		// Send all but the last entry, then stall.
+		i := strings.LastIndex(body, ",{")
+		fmt.Fprint(w, body[:i+1])
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}
+	srv := httptest.NewTLSServer(f)
+	defer srv.Close()
+	transport := serverTransport(srv)
+	defer transport.CloseIdleConnections()
+	s, _ := f.dial(t, testToken(1), WithHTTPClient(&http.Client{Transport: transport}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	got, err := s.TailContext(ctx, time.Hour)
+	if err != context.DeadlineExceeded {
+		t.Errorf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d entries, want 2", len(got))
+	}
+	for i, e := range got {
+		if want := entries[i+1].Value; e.Value != want {
+			t.Errorf("entry %d has value %d, want %d", i, e.Value, want)
+		}
+	}
+}
+
+func TestTailMaxEntries(t *testing.T) {
+	values := make([]int, 95)
+	for i := range values {
+		values[i] = 40 + i
+	}
+	entries := readings(values...)
+	var maxCount string
+	f := &fakeDexcom{}
+	f.query = func(w http.ResponseWriter, r *http.Request) {
+		// Dexcom is not to be trusted to honor maxCount.
+		maxCount = r.URL.Query().Get("maxCount")
+		serveEntries(entries)(w, r)
+	}
+	s, log := f.dial(t, testToken(1), WithMaxEntries(10))
+
+	got, err := s.Tail(24 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 10 {
+		t.Fatalf("got %d entries, want 10", len(got))
+	}
+	for i, e := range got {
+		if want := entries[len(entries)-10+i]; !e.Time.Equal(want.Time) || e.Value != want.Value {
+			t.Errorf("entry %d is %v, want %v", i, e, want)
+		}
+	}
+	if !log.contains("Truncated response to the latest 10 entries") {
+		t.Error("truncation not logged")
+	}
+	if maxCount != "10" {
+		t.Errorf("queried with maxCount %s, want 10", maxCount)
+	}
+}
+
+func TestTailMaxAge(t *testing.T) {
+	// Readings every five minutes; the last three are no older than 12m.
+	entries := readings(100, 110, 120, 130, 140, 150)
+	f := &fakeDexcom{query: serveEntries(entries)}
+	s, _ := f.dial(t, testToken(1), WithMaxAge(12*time.Minute))
+
+	got, err := s.Tail(24 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("got %d entries, want 3", len(got))
+	}
+	for i, e := range got {
+		if want := entries[3+i]; !e.Time.Equal(want.Time) || e.Value != want.Value {
+			t.Errorf("entry %d is %v, want %v", i, e, want)
+		}
+	}
+}
+
+func TestTailOrder(t *testing.T) {
+	entries := readings(100, 110, 120)
+	for _, c := range []struct {
+		name string
+		opts []Option
+		want []int
+	}{
+		{"default", nil, []int{100, 110, 120}},
+		{"ascending", []Option{WithOrder(OrderAscending)}, []int{100, 110, 120}},
+		{"descending", []Option{WithOrder(OrderDescending)}, []int{120, 110, 100}},
+	} {
+		f := &fakeDexcom{query: serveEntries(entries)}
+		s, _ := f.dial(t, testToken(1), c.opts...)
+		got, err := s.Tail(time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		values := make([]int, len(got))
+		for i, e := range got {
+			values[i] = e.Value
+		}
+		if fmt.Sprint(values) != fmt.Sprint(c.want) {
+			t.Errorf("%s: got %v, want %v", c.name, values, c.want)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package dex
+
+import "context"
+
+// An Authenticator obtains a session token from Dexcom. Sessions
+// call their Authenticator to log in and to refresh expired tokens.
+type Authenticator interface {
+	Authenticate(ctx context.Context) (token string, err error)
+}
+
+// WithAuthenticator makes the session log in with a, rather than by
+// posting its username and password to Dexcom's publisher account
+// login.
+func WithAuthenticator(a Authenticator) Option {
+	return func(s *Session) {
+		s.auth = a
+	}
+}
@@ -0,0 +1,148 @@
+package dex
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReadJSONL reads entries from r as JSON Lines, as written by
+// StreamJSONL or an EntryLog. Blank lines are skipped.
+func ReadJSONL(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, errors.New(fmt.Sprintf("Line %d: %v", line, err))
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// ReadCSV reads entries from r as CSV. The first record names the
+// columns: "time" (RFC 3339) and "mgdl" are required, and
+// "direction" (a Dir's name) is optional; others are ignored.
+func ReadCSV(r io.Reader) ([]Entry, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+	cols := map[string]int{"direction": -1}
+	for i, name := range header {
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"time", "mgdl"} {
+		if _, ok := cols[name]; !ok {
+			return nil, errors.New(fmt.Sprintf("Missing column %q", name))
+		}
+	}
+
+	var entries []Entry
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return entries, nil
+		} else if err != nil {
+			return nil, err
+		}
+		field := func(name string) string {
+			if i := cols[name]; i >= 0 && i < len(rec) {
+				return strings.TrimSpace(rec[i])
+			}
+			return ""
+		}
+
+		t, err := time.Parse(time.RFC3339, field("time"))
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Line %d: %v", line, err))
+		}
+		v, err := strconv.ParseFloat(field("mgdl"), 64)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Line %d: %v", line, err))
+		}
+		dir := None
+		if name := field("direction"); name != "" {
+			if dir, err = ParseDir(name); err != nil {
+				return nil, errors.New(fmt.Sprintf("Line %d: %v", line, err))
+			}
+		}
+		entries = append(entries, Entry{Time: t, Value: int(math.Round(v)), Dir: dir})
+	}
+}
+
+// A BacktestSource is a Source of entries recorded in a file, for
+// replaying history, as to see when triggers would have fired. It
+// streams its entries as fast as they are consumed, then ends.
+type BacktestSource struct {
+	entries []Entry
+}
+
+// NewBacktestSource reads the entries in the file at path, as CSV
+// if its name ends in ".csv" and as JSON Lines otherwise, keeping
+// those with times in [from, to). A zero from or to leaves the
+// range open at that end.
+func NewBacktestSource(path string, from, to time.Time) (*BacktestSource, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []Entry
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		entries, err = ReadCSV(file)
+	} else {
+		entries, err = ReadJSONL(file)
+	}
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("%s: %v", path, err))
+	}
+
+	kept := entries[:0]
+	for _, e := range entries {
+		if (from.IsZero() || !e.Time.Before(from)) && (to.IsZero() || e.Time.Before(to)) {
+			kept = append(kept, e)
+		}
+	}
+	sort.SliceStable(kept, func(i, j int) bool {
+		return kept[i].Time.Before(kept[j].Time)
+	})
+	return &BacktestSource{entries: kept}, nil
+}
+
+// Entries returns the source's entries, oldest first.
+func (b *BacktestSource) Entries() []Entry {
+	return b.entries
+}
+
+func (b *BacktestSource) StreamContext(ctx context.Context, begin time.Time, out chan<- Entry) {
+	defer close(out)
+	for _, e := range b.entries {
+		if !e.Time.After(begin) {
+			continue
+		}
+		select {
+		case out <- e:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package dex
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// An EntryLog is an append-only archive of entries in a directory,
+// kept as one JSON Lines file per day (UTC) of entry time, named
+// as 2006-01-02.jsonl. It is safe for concurrent use.
+type EntryLog struct {
+	dir string
+
+	mu   sync.Mutex
+	day  string
+	file *os.File
+}
+
+// OpenEntryLog opens the entry log in dir, creating dir if needed.
+func OpenEntryLog(dir string) (*EntryLog, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &EntryLog{dir: dir}, nil
+}
+
+// The name of each day's file, less its extension.
+const dayFormat = "2006-01-02"
+
+func (l *EntryLog) path(day string) string {
+	return filepath.Join(l.dir, day+".jsonl")
+}
+
+// Append e to the log, in the file for its day, keeping its Raw
+// JSON, if any.
+func (l *EntryLog) Append(e Entry) error {
+	line, err := json.Marshal(RawEntry(e))
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	day := e.Time.UTC().Format(dayFormat)
+	if l.file == nil || day != l.day {
+		if l.file != nil {
+			l.file.Close()
+			l.file = nil
+		}
+		f, err := os.OpenFile(l.path(day), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return err
+		}
+		l.file, l.day = f, day
+	}
+
+	_, err = l.file.Write(append(line, '\n'))
+	return err
+}
+
+// Replay returns the logged entries with times in [from, to), in
+// order of time. Only the days on file are read, so from and to may
+// be far apart, as the zero time is from all time.
+func (l *EntryLog) Replay(from, to time.Time) ([]Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	days, err := l.days()
+	if err != nil {
+		return nil, err
+	}
+	first, last := from.UTC().Format(dayFormat), to.UTC().Format(dayFormat)
+	var entries []Entry
+	for _, day := range days {
+		if day < first || day > last {
+			continue
+		}
+		if err := l.read(day, from, to, &entries); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.Before(entries[j].Time)
+	})
+	return entries, nil
+}
+
+// The days with files in the log, in order.
+func (l *EntryLog) days() ([]string, error) {
+	infos, err := ioutil.ReadDir(l.dir)
+	if err != nil {
+		return nil, err
+	}
+	var days []string
+	for _, info := range infos {
+		name := info.Name()
+		day := strings.TrimSuffix(name, ".jsonl")
+		if day == name || info.IsDir() {
+			continue
+		}
+		if _, err := time.Parse(dayFormat, day); err != nil {
+			continue
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+func (l *EntryLog) read(day string, from, to time.Time, entries *[]Entry) error {
+	file, err := os.Open(l.path(day))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return err
+		}
+		if !e.Time.Before(from) && e.Time.Before(to) {
+			*entries = append(*entries, e)
+		}
+	}
+	return scanner.Err()
+}
+
+// Close the log.
+func (l *EntryLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
@@ -0,0 +1,106 @@
+package dex
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEntryLogRoundTrip(t *testing.T) {
+	l, err := OpenEntryLog(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// Entries either side of midnight, so in two files.
+	midnight := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{Time: midnight.Add(-5 * time.Minute), Value: 100, Dir: Flat, Noise: 1},
+		{Time: midnight, Value: 110, Dir: FortyFiveUp, Skew: 90 * time.Second,
+			Raw: `{"WT":"Date(1577923200000)","Value":110}`},
+		{Time: midnight.Add(5 * time.Minute), Value: 125, Dir: SingleUp, Skew: -time.Hour, Seq: 3},
+	}
+	for _, e := range entries {
+		if err := l.Append(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := l.Replay(midnight.Add(-time.Hour), midnight.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(entries) {
+		t.Fatalf("replayed %d entries, want %d", len(got), len(entries))
+	}
+	for i := range got {
+		// Compare times as instants, whatever their locations.
+		if !got[i].Time.Equal(entries[i].Time) {
+			t.Errorf("entry %d at %v, want %v", i, got[i].Time, entries[i].Time)
+		}
+		got[i].Time = entries[i].Time
+		if !reflect.DeepEqual(got[i], entries[i]) {
+			t.Errorf("entry %d is %+v, want %+v", i, got[i], entries[i])
+		}
+	}
+
+	got, err = l.Replay(midnight, midnight.Add(5*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Value != 110 {
+		t.Errorf("replayed %v, want the entry at midnight", got)
+	}
+}
+
+func TestEntryLogReplayAll(t *testing.T) {
+	dir := t.TempDir()
+	l, err := OpenEntryLog(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// Entries years apart, and files that are not the log's.
+	var entries []Entry
+	for _, year := range []int{2019, 2021, 2024} {
+		e := Entry{Time: time.Date(year, 6, 1, 12, 0, 0, 0, time.UTC), Value: year - 1900, Dir: Flat}
+		if err := l.Append(e); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, e)
+	}
+	for _, name := range []string{"notes.jsonl", "2020-01-01.txt"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte("not an entry\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "2022-01-01.jsonl"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := l.Replay(time.Time{}, time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(entries) {
+		t.Fatalf("replayed %d entries, want %d", len(got), len(entries))
+	}
+	for i := range got {
+		if !got[i].Time.Equal(entries[i].Time) || got[i].Value != entries[i].Value {
+			t.Errorf("entry %d is %v, want %v", i, got[i], entries[i])
+		}
+	}
+
+	got, err = l.Replay(time.Time{}, entries[1].Time)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Value != entries[0].Value {
+		t.Errorf("replayed %v, want the first entry", got)
+	}
+}
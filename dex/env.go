@@ -0,0 +1,114 @@
+package dex
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DialFromEnv is like Dial, but takes credentials from the
+// environment variables DEXCOM_USER and DEXCOM_PASS. Each variable
+// that is unset falls back to the login or password of the ~/.netrc
+// entry for the Dexcom Share host; the environment always takes
+// precedence.
+func DialFromEnv(opts ...Option) (*Session, error) {
+	user := os.Getenv("DEXCOM_USER")
+	pass := os.Getenv("DEXCOM_PASS")
+
+	if user == "" || pass == "" {
+		nuser, npass := netrc(shareHost())
+		if user == "" {
+			user = nuser
+		}
+		if pass == "" {
+			pass = npass
+		}
+	}
+
+	var missing []string
+	if user == "" {
+		missing = append(missing, "DEXCOM_USER")
+	}
+	if pass == "" {
+		missing = append(missing, "DEXCOM_PASS")
+	}
+	if len(missing) > 0 {
+		return nil, errors.New(fmt.Sprintf(
+			"Missing credentials: %s not set", strings.Join(missing, ", ")))
+	}
+
+	return Dial(user, pass, opts...)
+}
+
+func shareHost() string {
+	return usHost
+}
+
+// Look up the login and password for host in $HOME/.netrc.
+// A "default" entry is used if no machine matches.
+func netrc(host string) (login, password string) {
+	file, err := os.Open(filepath.Join(os.Getenv("HOME"), ".netrc"))
+	if err != nil {
+		return "", ""
+	}
+	defer file.Close()
+
+	var toks []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		toks = append(toks, strings.Fields(line)...)
+	}
+
+	var (
+		found, isDefault bool
+		dlogin, dpass    string
+		cur              string
+	)
+	for i := 0; i < len(toks); i++ {
+		switch toks[i] {
+		case "machine":
+			if found {
+				return login, password
+			}
+			if i+1 < len(toks) {
+				i++
+				cur = toks[i]
+				found = cur == host
+			}
+			isDefault = false
+		case "default":
+			if found {
+				return login, password
+			}
+			isDefault = true
+		case "login", "password":
+			if i+1 >= len(toks) {
+				break
+			}
+			key, val := toks[i], toks[i+1]
+			i++
+			switch {
+			case found && key == "login":
+				login = val
+			case found:
+				password = val
+			case isDefault && key == "login":
+				dlogin = val
+			case isDefault:
+				dpass = val
+			}
+		}
+	}
+
+	if found {
+		return login, password
+	}
+	return dlogin, dpass
+}
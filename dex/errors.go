@@ -0,0 +1,78 @@
+package dex
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrNoCredentials is returned when a session needs to log in but
+// was given no username and password.
+var ErrNoCredentials = errors.New("dexcom: no credentials to log in with")
+
+// ErrAuth is returned when Dexcom accepts a login but returns no
+// usable session token.
+var ErrAuth = errors.New("dexcom: login returned no session token")
+
+// ErrInvalidEntry matches, by errors.Is, the errors returned by
+// Entry.Valid.
+var ErrInvalidEntry = errors.New("dexcom: invalid entry")
+
+// An InvalidEntryError describes why an entry is not usable.
+type InvalidEntryError struct {
+	Reason string
+}
+
+func (e *InvalidEntryError) Error() string {
+	return "dexcom: invalid entry: " + e.Reason
+}
+
+// Is tells whether target is ErrInvalidEntry.
+func (e *InvalidEntryError) Is(target error) bool {
+	return target == ErrInvalidEntry
+}
+
+// StatusError is returned when Dexcom responds with an error status.
+type StatusError struct {
+	StatusCode int
+	Code       string // The error code in the response body, if any.
+}
+
+// The error codes with which Dexcom reports an expired or unknown
+// session, with status 500.
+var sessionCodes = map[string]bool{
+	"SessionIdNotFound": true,
+	"SessionNotValid":   true,
+}
+
+// Make a StatusError from the error response resp, reading the code
+// from its body.
+func newStatusError(resp *http.Response) *StatusError {
+	var body struct {
+		Code string `json:"Code"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body)
+	return &StatusError{StatusCode: resp.StatusCode, Code: body.Code}
+}
+
+func (e *StatusError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("dexcom: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Code)
+	}
+	return fmt.Sprintf("dexcom: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Auth tells whether the error was an authentication failure,
+// including an expired or unknown session.
+func (e *StatusError) Auth() bool {
+	return e.StatusCode == http.StatusUnauthorized ||
+		e.StatusCode == http.StatusForbidden ||
+		sessionCodes[e.Code]
+}
+
+// Temporary tells whether the request may succeed if retried.
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode >= 500 && !e.Auth()
+}
@@ -0,0 +1,133 @@
+package dex
+
+import "math"
+
+// Smooth returns a channel of the entries from in with their values
+// replaced by an exponential moving average: each value is alpha
+// times the entry's value plus 1-alpha times the previous smoothed
+// value. Alpha above 1, or not above 0, as NaN, is taken as 1,
+// which leaves values unchanged.
+// Smoothing alters readings and delays changes, so smoothed entries
+// are meant for display, not for alarms or treatment decisions.
+// The returned channel is closed when in is.
+func Smooth(in <-chan Entry, alpha float64) <-chan Entry {
+	return Pipe(in, SmoothStage(alpha))
+}
+
+// SmoothStage is a Stage that smooths entries like Smooth.
+func SmoothStage(alpha float64) Stage {
+	if !(alpha > 0) || alpha > 1 {
+		alpha = 1
+	}
+
+	var (
+		avg   float64
+		first = true
+	)
+	return func(e Entry, emit func(Entry)) {
+		if first {
+			avg, first = float64(e.Value), false
+		} else {
+			avg = alpha*float64(e.Value) + (1-alpha)*avg
+		}
+		e.Value = int(math.Round(avg))
+		emit(e)
+	}
+}
+
+// ArtifactFilter configures FilterArtifacts.
+type ArtifactFilter struct {
+	// DropRate is the rate of fall, in mg/dL/min, beyond which a
+	// reading is suspect. Zero means 3.
+	DropRate float64
+
+	// Hold is the number of readings after a suspect one that
+	// FilterArtifacts waits for it to recover. Zero means 1.
+	Hold int
+}
+
+// FilterArtifacts returns a channel of the entries from in, less
+// those that look like artifacts, such as compression lows: sudden
+// drops that recover within a few readings. A reading that falls
+// faster than f.DropRate is held back for up to f.Hold further
+// readings. If one of those recovers at least half of the drop, the
+// held readings are discarded; otherwise they are passed on, late.
+//
+// The filter thus delays genuine rapid drops, including real lows,
+// by up to f.Hold readings (five minutes each), in exchange for
+// fewer false alarms. The returned channel is closed when in is.
+func FilterArtifacts(in <-chan Entry, f ArtifactFilter) <-chan Entry {
+	a := newArtifacts(f)
+	out := make(chan Entry)
+	go func() {
+		defer close(out)
+		emit := func(e Entry) { out <- e }
+		for e := range in {
+			a.push(e, emit)
+		}
+		a.flush(emit)
+	}()
+	return out
+}
+
+// ArtifactStage is a Stage that filters artifacts like
+// FilterArtifacts, except that readings still held when the
+// pipeline's input closes are lost.
+func ArtifactStage(f ArtifactFilter) Stage {
+	return newArtifacts(f).push
+}
+
+type artifacts struct {
+	f    ArtifactFilter
+	prev *Entry // The last entry passed on.
+	held []Entry
+}
+
+func newArtifacts(f ArtifactFilter) *artifacts {
+	if f.DropRate <= 0 {
+		f.DropRate = 3
+	}
+	if f.Hold <= 0 {
+		f.Hold = 1
+	}
+	return &artifacts{f: f}
+}
+
+func (a *artifacts) pass(e Entry, emit func(Entry)) {
+	emit(e)
+	a.prev = &e
+}
+
+func (a *artifacts) push(e Entry, emit func(Entry)) {
+	if len(a.held) > 0 {
+		drop := a.prev.Value - a.held[0].Value
+		if e.Value >= a.held[0].Value+drop/2 {
+			// Recovered: the held readings were artifacts.
+			a.held = nil
+			a.pass(e, emit)
+			return
+		}
+		a.held = append(a.held, e)
+		if len(a.held) > a.f.Hold {
+			a.flush(emit)
+		}
+		return
+	}
+
+	if a.prev != nil {
+		minutes := e.Time.Sub(a.prev.Time).Minutes()
+		if minutes > 0 && float64(a.prev.Value-e.Value)/minutes > a.f.DropRate {
+			a.held = append(a.held, e)
+			return
+		}
+	}
+	a.pass(e, emit)
+}
+
+// Flush passes on any held readings.
+func (a *artifacts) flush(emit func(Entry)) {
+	for _, h := range a.held {
+		a.pass(h, emit)
+	}
+	a.held = nil
+}
@@ -0,0 +1,34 @@
+package dex
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"text/template"
+	"time"
+)
+
+// The template StreamFormat uses by default, giving lines such as
+//
+//	15:04 112 mg/dL ⇗
+var defaultFormat = template.Must(template.New("entry").Parse(
+	`{{.Time.Format "15:04"}} {{.Value}} mg/dL {{.Dir.Arrow}}` + "\n"))
+
+// StreamFormat is like StreamJSONL, but writes each entry as
+// formatted by executing tmpl with it. Templates may use the
+// entry's fields and methods, as in
+//
+//	{{.Time.Format "15:04"}} {{printf "%.1f" .Mmol}} {{.Dir.Arrow}}
+//
+// The output is written as is, so tmpl should end lines itself. A
+// nil tmpl gives the time, value and arrow, one entry per line.
+func StreamFormat(ctx context.Context, src Source, begin time.Time, w io.Writer, tmpl *template.Template) error {
+	if tmpl == nil {
+		tmpl = defaultFormat
+	}
+	return streamLines(ctx, src, begin, w, func(e Entry) ([]byte, error) {
+		var buf bytes.Buffer
+		err := tmpl.Execute(&buf, e)
+		return buf.Bytes(), err
+	})
+}
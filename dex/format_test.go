@@ -0,0 +1,59 @@
+package dex
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"text/template"
+	"time"
+)
+
+// An entrySource streams its entries newer than begin, then ends.
+type entrySource []Entry
+
+func (s entrySource) StreamContext(ctx context.Context, begin time.Time, out chan<- Entry) {
+	defer close(out)
+	for _, e := range s {
+		if !e.Time.After(begin) {
+			continue
+		}
+		select {
+		case out <- e:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func TestStreamFormat(t *testing.T) {
+	start := time.Date(2020, 1, 1, 9, 0, 0, 0, time.UTC)
+	src := entrySource{
+		{Time: start, Value: 100, Dir: Flat},
+		{Time: start.Add(5 * time.Minute), Value: 112, Dir: FortyFiveUp},
+		{Time: start.Add(10 * time.Minute), Value: 126, Dir: SingleUp},
+	}
+	custom := template.Must(template.New("custom").Parse(
+		`{{.Time.Format "15:04"}},{{printf "%.1f" .Mmol}},{{.Dir}}` + "\n"))
+	for _, c := range []struct {
+		name string
+		tmpl *template.Template
+		want string
+	}{
+		{"default", nil, "09:05 112 mg/dL ⇗\n09:10 126 mg/dL ↑\n"},
+		{"custom", custom, "09:05,6.2,FortyFiveUp\n09:10,7.0,SingleUp\n"},
+	} {
+		var buf bytes.Buffer
+		if err := StreamFormat(context.Background(), src, start, &buf, c.tmpl); err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if got := buf.String(); got != c.want {
+			t.Errorf("%s: wrote %q, want %q", c.name, got, c.want)
+		}
+	}
+
+	// Template errors end the stream.
+	bad := template.Must(template.New("bad").Parse(`{{.Nope}}`))
+	if err := StreamFormat(context.Background(), src, start, &bytes.Buffer{}, bad); err == nil {
+		t.Error("StreamFormat succeeded with a failing template")
+	}
+}
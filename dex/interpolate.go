@@ -0,0 +1,40 @@
+package dex
+
+import (
+	"math"
+	"time"
+)
+
+// Interpolate fills gaps in entries (ordered oldest-first) with
+// linearly interpolated entries spaced interval apart. Gaps longer
+// than maxGap are left as holes. Interpolated entries are estimates,
+// not readings: they are marked Synthetic and carry no direction.
+// Original entries are returned unchanged.
+func Interpolate(entries []Entry, interval, maxGap time.Duration) []Entry {
+	if interval <= 0 || len(entries) < 2 {
+		return entries
+	}
+
+	out := make([]Entry, 0, len(entries))
+	for i := range entries {
+		if i > 0 {
+			e0, e1 := entries[i-1], entries[i]
+			gap := e1.Time.Sub(e0.Time)
+			if gap > interval && gap <= maxGap {
+				for t := e0.Time.Add(interval); e1.Time.Sub(t) >= interval/2; t = t.Add(interval) {
+					frac := float64(t.Sub(e0.Time)) / float64(gap)
+					v := float64(e0.Value) + frac*float64(e1.Value-e0.Value)
+					out = append(out, Entry{
+						Time:      t,
+						Value:     int(math.Round(v)),
+						Dir:       None,
+						Synthetic: true,
+					})
+				}
+			}
+		}
+		out = append(out, entries[i])
+	}
+
+	return out
+}
@@ -0,0 +1,37 @@
+package dex
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"time"
+)
+
+// WithStartupJitter delays Stream's first poll by a random duration
+// up to max, so that monitors started together do not poll Dexcom
+// in lockstep. The delay is drawn from the source given
+// WithJitterSource or, by default, one seeded by the username.
+func WithStartupJitter(max time.Duration) Option {
+	return func(s *Session) {
+		s.jitter = max
+	}
+}
+
+// WithJitterSource sets the source of randomness for startup jitter.
+func WithJitterSource(src rand.Source) Option {
+	return func(s *Session) {
+		s.jitterSrc = src
+	}
+}
+
+func (s *Session) startupJitter() time.Duration {
+	if s.jitter <= 0 {
+		return 0
+	}
+	src := s.jitterSrc
+	if src == nil {
+		h := fnv.New64a()
+		h.Write([]byte(s.user))
+		src = rand.NewSource(int64(h.Sum64()))
+	}
+	return time.Duration(rand.New(src).Int63n(int64(s.jitter)))
+}
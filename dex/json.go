@@ -0,0 +1,141 @@
+package dex
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// The number of mg/dL in one mmol/L of blood glucose.
+const MgdlPerMmol = 18.0182
+
+// Mmol returns the entry's blood glucose level in mmol/L.
+func (e Entry) Mmol() float64 {
+	return float64(e.Value) / MgdlPerMmol
+}
+
+// ParseDir returns the Dir with the given name, as returned by
+// Dir.String.
+func ParseDir(name string) (Dir, error) {
+	for d := None; d <= RateOutOfRange; d++ {
+		if d.String() == name {
+			return d, nil
+		}
+	}
+	return None, errors.New(fmt.Sprintf("Unknown direction %q", name))
+}
+
+// An mgdl is a glucose value in mg/dL. It is encoded as an integer,
+// but decoded from any JSON number, or a string containing one, as
+// some third-party sources give values such as 120.0 or "120".
+// Fractional values are rounded to the nearest integer, halves away
+// from zero.
+type mgdl int
+
+func (m *mgdl) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		var str string
+		if err := json.Unmarshal(b, &str); err != nil {
+			return err
+		}
+		b = []byte(str)
+	}
+
+	var num float64
+	if err := json.Unmarshal(b, &num); err != nil {
+		return errors.New(fmt.Sprintf("Invalid glucose value %s", b))
+	}
+	*m = mgdl(math.Round(num))
+	return nil
+}
+
+type entryObj struct {
+	Time      time.Time `json:"time"`
+	Mgdl      mgdl      `json:"mgdl"`
+	Mmol      float64   `json:"mmol"`
+	Direction string    `json:"direction"`
+	Arrow     string    `json:"arrow"`
+	Noise     int       `json:"noise,omitempty"`
+	Skew      string    `json:"skew,omitempty"`
+	Synthetic bool      `json:"synthetic,omitempty"`
+	Raw       string    `json:"raw,omitempty"`
+	Seq       uint64    `json:"seq,omitempty"`
+}
+
+func (e Entry) obj() entryObj {
+	obj := entryObj{
+		Time:      e.Time,
+		Mgdl:      mgdl(e.Value),
+		Mmol:      math.Round(e.Mmol()*10) / 10,
+		Direction: e.Dir.String(),
+		Arrow:     e.Dir.Arrow(),
+		Noise:     e.Noise,
+		Synthetic: e.Synthetic,
+		Seq:       e.Seq,
+	}
+	if e.Skew != 0 {
+		obj.Skew = e.Skew.String()
+	}
+	return obj
+}
+
+// MarshalJSON encodes the entry as an object with the fields
+// "time" (RFC 3339), "mgdl", "mmol", "direction" (the Dir's
+// name) and "arrow", and "skew" (as "1m30s") if it is not zero.
+// Raw is omitted; see RawEntry.
+func (e Entry) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.obj())
+}
+
+// UnmarshalJSON decodes an entry encoded by MarshalJSON. The
+// "mmol" and "arrow" fields are derived, and so are ignored.
+func (e *Entry) UnmarshalJSON(b []byte) error {
+	var obj entryObj
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return err
+	}
+
+	dir := None
+	if obj.Direction != "" {
+		var err error
+		if dir, err = ParseDir(obj.Direction); err != nil {
+			return err
+		}
+	}
+
+	var skew time.Duration
+	if obj.Skew != "" {
+		var err error
+		if skew, err = time.ParseDuration(obj.Skew); err != nil {
+			return err
+		}
+	}
+
+	*e = Entry{
+		Time:      obj.Time,
+		Value:     int(obj.Mgdl),
+		Dir:       dir,
+		Raw:       obj.Raw,
+		Skew:      skew,
+		Noise:     obj.Noise,
+		Synthetic: obj.Synthetic,
+		Seq:       obj.Seq,
+	}
+	return nil
+}
+
+// RawEntry is an Entry whose JSON encoding also includes its Raw
+// field, as "raw".
+type RawEntry Entry
+
+func (e RawEntry) MarshalJSON() ([]byte, error) {
+	obj := Entry(e).obj()
+	obj.Raw = e.Raw
+	return json.Marshal(obj)
+}
+
+func (e *RawEntry) UnmarshalJSON(b []byte) error {
+	return (*Entry)(e).UnmarshalJSON(b)
+}
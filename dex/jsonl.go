@@ -0,0 +1,109 @@
+package dex
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// The locks of the writers StreamJSONL and StreamFormat are writing
+// to, which concurrent streams may share, so that their lines do not
+// interleave. Each is kept while any stream uses it.
+var (
+	writersMu sync.Mutex
+	writers   = make(map[io.Writer]*writerLock)
+)
+
+type writerLock struct {
+	sync.Mutex
+	refs int
+}
+
+// Return the lock of w, shared with other streams writing to it,
+// and a function to call when done with it.
+func lockWriter(w io.Writer) (*sync.Mutex, func()) {
+	if !reflect.TypeOf(w).Comparable() {
+		// Such a writer cannot be shared but by copying it.
+		return new(sync.Mutex), func() {}
+	}
+
+	writersMu.Lock()
+	defer writersMu.Unlock()
+	l := writers[w]
+	if l == nil {
+		l = new(writerLock)
+		writers[w] = l
+	}
+	l.refs++
+	return &l.Mutex, func() {
+		writersMu.Lock()
+		defer writersMu.Unlock()
+		if l.refs--; l.refs == 0 {
+			delete(writers, w)
+		}
+	}
+}
+
+// StreamJSONL streams entries newer than begin from src to w as JSON
+// Lines: one JSON object per entry, as encoded by Entry.MarshalJSON,
+// each followed by a newline. Each line is written whole, and w is
+// flushed after each if it has a Flush method. StreamJSONL returns
+// when the stream ends, ctx is done, or a write fails, returning
+// the error, if any.
+func StreamJSONL(ctx context.Context, src Source, begin time.Time, w io.Writer) error {
+	return streamLines(ctx, src, begin, w, func(e Entry) ([]byte, error) {
+		line, err := json.Marshal(e)
+		return append(line, '\n'), err
+	})
+}
+
+// Stream entries newer than begin from src to w, each as formatted
+// by format.
+func streamLines(ctx context.Context, src Source, begin time.Time, w io.Writer, format func(Entry) ([]byte, error)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	mu, done := lockWriter(w)
+	defer done()
+
+	entries := make(chan Entry)
+	go src.StreamContext(ctx, begin, entries)
+	defer discard(entries)
+
+	for {
+		select {
+		case e, ok := <-entries:
+			if !ok {
+				return ctx.Err()
+			}
+			line, err := format(e)
+			if err != nil {
+				return err
+			}
+			if err := writeLine(mu, w, line); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func writeLine(mu *sync.Mutex, w io.Writer, line []byte) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, err := w.Write(line); err != nil {
+		return err
+	}
+	switch f := w.(type) {
+	case interface{ Flush() error }:
+		return f.Flush()
+	case interface{ Flush() }:
+		f.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package dex
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A stuckWriter blocks in Write until released, signalling writing
+// on its first write.
+type stuckWriter struct {
+	writing, release chan struct{}
+}
+
+func (w *stuckWriter) Write(p []byte) (int, error) {
+	select {
+	case w.writing <- struct{}{}:
+	default:
+	}
+	<-w.release
+	return len(p), nil
+}
+
+func TestStreamJSONLWritersIndependent(t *testing.T) {
+	start := time.Date(2020, 1, 1, 9, 0, 0, 0, time.UTC)
+	src := entrySource{
+		{Time: start, Value: 100, Dir: Flat},
+		{Time: start.Add(5 * time.Minute), Value: 110, Dir: Flat},
+	}
+
+	stuck := &stuckWriter{make(chan struct{}, 1), make(chan struct{})}
+	stuckDone := make(chan error)
+	go func() {
+		stuckDone <- StreamJSONL(context.Background(), src, start.Add(-time.Minute), stuck)
+	}()
+	<-stuck.writing
+
+	// A stream to another writer is not held up by the stuck one.
+	var buf bytes.Buffer
+	done := make(chan error)
+	go func() {
+		done <- StreamJSONL(context.Background(), src, start.Add(-time.Minute), &buf)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream blocked by a stuck writer")
+	}
+	if n := strings.Count(buf.String(), "\n"); n != 2 {
+		t.Errorf("wrote %d lines, want 2", n)
+	}
+
+	close(stuck.release)
+	if err := <-stuckDone; err != nil {
+		t.Fatal(err)
+	}
+	writersMu.Lock()
+	defer writersMu.Unlock()
+	if len(writers) != 0 {
+		t.Errorf("%d writer locks remain", len(writers))
+	}
+}
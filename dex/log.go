@@ -0,0 +1,99 @@
+package dex
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// A Logger records a session's operational messages. The standard
+// library's *log.Logger is a Logger.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// WithLogger directs the session's log messages to l, rather than
+// the standard logger.
+func WithLogger(l Logger) Option {
+	return func(s *Session) {
+		s.logger = l
+	}
+}
+
+func (s *Session) logf(format string, v ...interface{}) {
+	if s.logger == nil {
+		log.Printf(format, v...)
+		return
+	}
+	s.logger.Printf(format, v...)
+}
+
+// An Event is an operational event of a session, such as a
+// successful sample. Events marshal to JSON.
+type Event interface {
+	Kind() string
+	String() string
+}
+
+// An EventLogger is a Logger that also records events in structured
+// form. Sessions with other Loggers log events' String.
+type EventLogger interface {
+	Logger
+	LogEvent(ev Event)
+}
+
+// SampleEvent records that Stream received new entries.
+type SampleEvent struct {
+	Entries int           `json:"entries"` // The number of new entries.
+	Penalty time.Duration `json:"penalty"` // The polling delay incurred.
+}
+
+// GapEvent records that Stream found readings missing.
+type GapEvent struct {
+	From time.Time     `json:"from"` // The time of the last entry before the gap.
+	Gap  time.Duration `json:"gap"`  // The time until the next entry.
+}
+
+// BackoffEvent records that a session is waiting to retry Dexcom.
+type BackoffEvent struct {
+	Attempt int           `json:"attempt"`
+	Wait    time.Duration `json:"wait"`
+	Reason  string        `json:"reason"`
+}
+
+// RefreshEvent records that a session logged in again.
+type RefreshEvent struct {
+	Err string `json:"err,omitempty"` // The error, if the login failed.
+}
+
+func (SampleEvent) Kind() string  { return "sample" }
+func (GapEvent) Kind() string     { return "gap" }
+func (BackoffEvent) Kind() string { return "backoff" }
+func (RefreshEvent) Kind() string { return "refresh" }
+
+func (ev SampleEvent) String() string {
+	return fmt.Sprintf("Sampled with penalty %v", ev.Penalty)
+}
+
+func (ev GapEvent) String() string {
+	return fmt.Sprintf("Gap of %v after %v", ev.Gap, ev.From)
+}
+
+func (ev BackoffEvent) String() string {
+	return fmt.Sprintf("Backing off %v after %s (attempt %d)", ev.Wait, ev.Reason, ev.Attempt)
+}
+
+func (ev RefreshEvent) String() string {
+	if ev.Err != "" {
+		return fmt.Sprintf("Failed to refresh token: %s", ev.Err)
+	}
+	return "Refreshed token"
+}
+
+func (s *Session) event(ev Event) {
+	if l, ok := s.logger.(EventLogger); ok {
+		l.LogEvent(ev)
+		return
+	}
+	s.logf("%v\n", ev)
+}
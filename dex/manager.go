@@ -0,0 +1,100 @@
+package dex
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// A NamedEntry is an entry from one of a Manager's sessions.
+type NamedEntry struct {
+	Name string // The name of the session.
+	Entry
+}
+
+// A Manager holds named sessions, such as one per person followed.
+// It is safe for concurrent use.
+type Manager struct {
+	mu       sync.Mutex
+	sessions map[string]*Session
+}
+
+// NewManager returns a Manager with no sessions.
+func NewManager() *Manager {
+	return &Manager{sessions: make(map[string]*Session)}
+}
+
+// Dial a session, as by Dial, and add it to the manager under name.
+// Sessions of different names should be given distinct session
+// paths if their users may coincide.
+func (m *Manager) Dial(name, user, pass string, opts ...Option) (*Session, error) {
+	s, err := Dial(user, pass, opts...)
+	if err != nil {
+		return nil, err
+	}
+	m.Add(name, s)
+	return s, nil
+}
+
+// Add s to the manager under name, replacing any session of the
+// same name.
+func (m *Manager) Add(name string, s *Session) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[name] = s
+}
+
+// Get returns the session with the given name.
+func (m *Manager) Get(name string) (*Session, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[name]
+	return s, ok
+}
+
+// Names returns the names of the manager's sessions, in order.
+func (m *Manager) Names() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.sessions))
+	for name := range m.sessions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// StreamAll streams entries newer than begin from each of the
+// manager's sessions to out, tagged with the session's name. Each
+// session is streamed as by StreamRetry, so it backs off and
+// reconnects independently of the others. Out is closed once ctx
+// is done and all streams have stopped.
+func (m *Manager) StreamAll(ctx context.Context, begin time.Time, out chan<- NamedEntry) {
+	defer close(out)
+
+	m.mu.Lock()
+	sessions := make(map[string]*Session, len(m.sessions))
+	for name, s := range m.sessions {
+		sessions[name] = s
+	}
+	m.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for name, s := range sessions {
+		in := make(chan Entry)
+		go StreamRetry(ctx, s, begin, in)
+
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			for e := range in {
+				select {
+				case out <- NamedEntry{Name: name, Entry: e}:
+				case <-ctx.Done():
+				}
+			}
+		}(name)
+	}
+	wg.Wait()
+}
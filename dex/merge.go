@@ -0,0 +1,130 @@
+package dex
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// How long MergeSources holds entries to put them in order.
+const reorderDelay = 30 * time.Second
+
+// Conflict is the policy MergeSources applies to entries from
+// different sources with the same time.
+type Conflict int
+
+const (
+	PreferFirst  Conflict = iota // Keep the entry of the earlier source.
+	PreferNewest                 // Keep the entry that arrived last.
+)
+
+type mergeSource struct {
+	sources []Source
+	policy  Conflict
+	delay   time.Duration // How long to hold entries; reorderDelay.
+}
+
+// MergeSources returns a Source that streams the entries of all of
+// sources, in order of time, and without duplicates: of entries with
+// the same time, it keeps that of the earliest source. Entries are
+// held briefly to be put in order; those that arrive after a later
+// entry has been emitted are dropped.
+func MergeSources(sources ...Source) Source {
+	return MergeSourcesWith(PreferFirst, sources...)
+}
+
+// MergeSourcesWith is like MergeSources, but resolves entries with
+// the same time according to policy.
+func MergeSourcesWith(policy Conflict, sources ...Source) Source {
+	return &mergeSource{sources: sources, policy: policy, delay: reorderDelay}
+}
+
+type sourced struct {
+	src     int
+	e       Entry
+	arrived time.Time
+}
+
+func (m *mergeSource) StreamContext(ctx context.Context, begin time.Time, out chan<- Entry) {
+	defer close(out)
+
+	in := make(chan sourced)
+	var wg sync.WaitGroup
+	for i, src := range m.sources {
+		ch := make(chan Entry)
+		go src.StreamContext(ctx, begin, ch)
+
+		wg.Add(1)
+		go func(i int, ch <-chan Entry) {
+			defer wg.Done()
+			for e := range ch {
+				select {
+				case in <- sourced{src: i, e: e, arrived: time.Now()}:
+				case <-ctx.Done():
+				}
+			}
+		}(i, ch)
+	}
+	go func() {
+		wg.Wait()
+		close(in)
+	}()
+
+	ticker := time.NewTicker(m.delay / 2)
+	defer ticker.Stop()
+
+	pending := make(map[time.Time]sourced)
+	last := begin
+
+	// Emit pending entries in order of time, up to the first that
+	// has not been held long enough, or all of them: an entry is not
+	// emitted while an earlier one may yet be.
+	emit := func(all bool) bool {
+		times := make([]time.Time, 0, len(pending))
+		for t := range pending {
+			times = append(times, t)
+		}
+		sort.Slice(times, func(i, j int) bool {
+			return times[i].Before(times[j])
+		})
+		for _, t := range times {
+			p := pending[t]
+			if !all && time.Since(p.arrived) < m.delay {
+				break
+			}
+			delete(pending, t)
+			select {
+			case out <- p.e:
+				last = p.e.Time
+			case <-ctx.Done():
+				return false
+			}
+		}
+		return true
+	}
+
+	for {
+		select {
+		case p, ok := <-in:
+			if !ok {
+				emit(true)
+				return
+			}
+			if !p.e.Time.After(last) {
+				continue
+			}
+			key := p.e.Time.UTC()
+			if q, ok := pending[key]; ok && m.policy == PreferFirst && q.src <= p.src {
+				continue
+			}
+			pending[key] = p
+		case <-ticker.C:
+			if !emit(false) {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package dex
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// A lateSource streams its entries after a delay, then lingers
+// before ending.
+type lateSource struct {
+	delay, linger time.Duration
+	entries       entrySource
+}
+
+func (s lateSource) StreamContext(ctx context.Context, begin time.Time, out chan<- Entry) {
+	time.Sleep(s.delay)
+	ch := make(chan Entry)
+	go s.entries.StreamContext(ctx, begin, ch)
+	for e := range ch {
+		select {
+		case out <- e:
+		case <-ctx.Done():
+		}
+	}
+	time.Sleep(s.linger)
+	close(out)
+}
+
+func TestMergeSourcesOverlapping(t *testing.T) {
+	const delay = 100 * time.Millisecond
+	start := time.Now().Truncate(time.Minute)
+	at := func(minutes, v int) Entry {
+		return Entry{Time: start.Add(time.Duration(minutes) * time.Minute), Value: v}
+	}
+	// The second source's entries arrive while the first's, some of
+	// them later, are held; both have an entry at 10m.
+	m := &mergeSource{
+		sources: []Source{
+			lateSource{0, 0, entrySource{at(0, 100), at(10, 110), at(20, 120)}},
+			lateSource{delay / 2, 4 * delay, entrySource{at(5, 205), at(10, 210), at(15, 215)}},
+		},
+		policy: PreferFirst,
+		delay:  delay,
+	}
+	out := make(chan Entry)
+	go m.StreamContext(context.Background(), start.Add(-time.Minute), out)
+	var got []int
+	for e := range out {
+		got = append(got, e.Value)
+	}
+	if want := []int{100, 205, 110, 215, 120}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,38 @@
+package dex
+
+import (
+	"net/http"
+	"strings"
+)
+
+// TailMeta describes the response to a successful Tail.
+type TailMeta struct {
+	StatusCode int // The status of the final response.
+	Tries      int // The number of requests made.
+
+	// Header holds the response's Age, Cache-Control, Date and
+	// Retry-After headers, and any rate limit headers (those
+	// beginning "X-Ratelimit" or "Ratelimit"), if present.
+	Header http.Header
+}
+
+var metaHeaders = []string{"Age", "Cache-Control", "Date", "Retry-After"}
+
+func newTailMeta(resp *http.Response, tries int) TailMeta {
+	meta := TailMeta{
+		StatusCode: resp.StatusCode,
+		Tries:      tries,
+		Header:     make(http.Header),
+	}
+	for _, key := range metaHeaders {
+		if v, ok := resp.Header[key]; ok {
+			meta.Header[key] = v
+		}
+	}
+	for key, v := range resp.Header {
+		if strings.HasPrefix(key, "X-Ratelimit") || strings.HasPrefix(key, "Ratelimit") {
+			meta.Header[key] = v
+		}
+	}
+	return meta
+}
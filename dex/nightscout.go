@@ -0,0 +1,192 @@
+package dex
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A NightscoutSource is a Source of the glucose entries uploaded to
+// a Nightscout server, read from its /api/v1/entries endpoint.
+type NightscoutSource struct {
+	// BaseURL is the server's address, such as
+	// "https://example.herokuapp.com".
+	BaseURL string
+
+	// APISecret, if set, authenticates requests, for servers that
+	// restrict reading.
+	APISecret string
+
+	// Client makes the requests; nil means http.DefaultClient.
+	Client *http.Client
+
+	// Interval is how often to poll while awaiting an entry that is
+	// due. Zero means a minute.
+	Interval time.Duration
+
+	// Logger records failures; nil means the standard logger.
+	Logger Logger
+}
+
+type nightscoutEntry struct {
+	Date      int64  `json:"date"` // Milliseconds since the epoch.
+	SGV       mgdl   `json:"sgv"`
+	Direction string `json:"direction"`
+	Noise     int    `json:"noise"`
+}
+
+// Nightscout's names of the directions it spells differently, and
+// of those Dexcom has no arrow for, mapped to the nearest.
+var nightscoutDirs = map[string]Dir{
+	"NONE":              None,
+	"NOT COMPUTABLE":    NotComputable,
+	"RATE OUT OF RANGE": RateOutOfRange,
+	"TripleUp":          DoubleUp,
+	"TripleDown":        DoubleDown,
+}
+
+// TailContext returns the entries of the last howlong, oldest first.
+func (n *NightscoutSource) TailContext(ctx context.Context, howlong time.Duration) ([]Entry, error) {
+	count := int(howlong/(5*time.Minute)) + 1
+	if count > defaultMaxEntries {
+		count = defaultMaxEntries
+	}
+	since := time.Now().Add(-howlong)
+	params := url.Values{
+		"count":            {fmt.Sprintf("%d", count)},
+		"find[date][$gte]": {fmt.Sprintf("%d", since.UnixNano()/int64(time.Millisecond))}}
+
+	u := strings.TrimRight(n.BaseURL, "/") + "/api/v1/entries/sgv.json?" + params.Encode()
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	if n.APISecret != "" {
+		sum := sha1.Sum([]byte(n.APISecret))
+		req.Header.Set("api-secret", hex.EncodeToString(sum[:]))
+	}
+
+	client := n.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer closeBody(resp)
+	if resp.StatusCode >= 400 {
+		return nil, &StatusError{StatusCode: resp.StatusCode}
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var raws []json.RawMessage
+	if err := json.Unmarshal(body, &raws); err != nil {
+		return nil, errors.New(fmt.Sprintf("Malformed Nightscout entries: %v", err))
+	}
+
+	entries := make([]Entry, 0, len(raws))
+	for _, raw := range raws {
+		e, err := parseNightscout(raw)
+		if err != nil {
+			n.logf("Skipping entry %s: %v\n", raw, err)
+			continue
+		}
+		if e.Time.Before(since) {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	// Nightscout returns entries newest first.
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.Before(entries[j].Time)
+	})
+	return entries, nil
+}
+
+func parseNightscout(raw json.RawMessage) (Entry, error) {
+	var ne nightscoutEntry
+	if err := json.Unmarshal(raw, &ne); err != nil {
+		return Entry{}, err
+	}
+	if ne.Date == 0 {
+		return Entry{}, errors.New("No date")
+	}
+
+	// The reading is good even if its direction is not: an unknown
+	// one is taken as None.
+	dir, ok := nightscoutDirs[ne.Direction]
+	if !ok {
+		dir, _ = ParseDir(ne.Direction)
+	}
+	return Entry{
+		Time:  time.Unix(ne.Date/1000, (ne.Date%1000)*int64(time.Millisecond)),
+		Value: int(ne.SGV),
+		Dir:   dir,
+		Raw:   string(raw),
+		Noise: ne.Noise,
+	}, nil
+}
+
+// StreamContext streams entries newer than begin as they are
+// uploaded, polling for each when it is due. Like Session's, the
+// stream ends, closing out, when a request fails.
+func (n *NightscoutSource) StreamContext(ctx context.Context, begin time.Time, out chan<- Entry) {
+	defer close(out)
+
+	interval := n.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	for {
+		ents, err := n.TailContext(ctx, time.Since(begin)+5*time.Minute)
+		if err != nil {
+			if ctx.Err() == nil {
+				n.logf("Failed to retrieve data: %v\n", err)
+			}
+			return
+		}
+		for _, e := range ents {
+			if !e.Time.After(begin) {
+				continue
+			}
+			select {
+			case out <- e:
+			case <-ctx.Done():
+				return
+			}
+			begin = e.Time
+		}
+
+		wait := time.Until(begin.Add(5 * time.Minute))
+		if wait < interval {
+			wait = interval
+		}
+		if !sleep(ctx, wait) {
+			return
+		}
+	}
+}
+
+func (n *NightscoutSource) logf(format string, v ...interface{}) {
+	if n.Logger == nil {
+		log.Printf(format, v...)
+		return
+	}
+	n.Logger.Printf(format, v...)
+}
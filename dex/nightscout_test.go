@@ -0,0 +1,58 @@
+package dex
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNightscoutTail(t *testing.T) {
+	now := time.Now().Truncate(time.Millisecond)
+	dirs := []string{"Flat", "NONE", "TripleUp", "TripleDown", "NOT COMPUTABLE", "Sideways", ""}
+	want := []Dir{Flat, None, DoubleUp, DoubleDown, NotComputable, None, None}
+	var secret string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/entries/sgv.json" {
+			http.NotFound(w, r)
+			return
+		}
+		secret = r.Header.Get("api-secret")
+		// Newest first, as Nightscout returns them.
+		strs := make([]string, len(dirs))
+		for i, dir := range dirs {
+			ms := now.Add(-time.Duration(i)*5*time.Minute).UnixNano() / int64(time.Millisecond)
+			strs[i] = fmt.Sprintf(`{"date":%d,"sgv":%d,"direction":%q}`, ms, 100+i, dir)
+		}
+		fmt.Fprint(w, "["+strings.Join(strs, ",")+"]")
+	}))
+	defer srv.Close()
+
+	log := &testLog{}
+	n := &NightscoutSource{BaseURL: srv.URL + "/", APISecret: "secret", Logger: log}
+	got, err := n.TailContext(context.Background(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(dirs) {
+		t.Fatalf("got %d entries, want %d; log: %v", len(got), len(dirs), log.lines)
+	}
+	for i, e := range got {
+		j := len(dirs) - 1 - i
+		if e.Value != 100+j || e.Dir != want[j] {
+			t.Errorf("entry %d is %d %v, want %d %v (from %q)", i, e.Value, e.Dir, 100+j, want[j], dirs[j])
+		}
+		if i > 0 && !e.Time.After(got[i-1].Time) {
+			t.Errorf("entry %d is out of order", i)
+		}
+	}
+	sum := sha1.Sum([]byte("secret"))
+	if want := hex.EncodeToString(sum[:]); secret != want {
+		t.Errorf("sent api-secret %q, want %q", secret, want)
+	}
+}
@@ -0,0 +1,154 @@
+package dex
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// An Option configures a Session.
+type Option func(*Session)
+
+func (s *Session) apply(opts []Option) {
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.client == nil && (s.idleConnTimeout != 0 || s.proxy != nil) {
+		t := newTransport()
+		t.IdleConnTimeout = s.idleConnTimeout
+		if s.proxy != nil {
+			t.Proxy = http.ProxyURL(s.proxy)
+		}
+		s.client = &http.Client{Transport: t}
+	}
+}
+
+// WithMaxAge makes Tail, and so Stream, drop entries older than
+// d. This filters on the client; Dexcom still returns the stale
+// entries.
+func WithMaxAge(d time.Duration) Option {
+	return func(s *Session) {
+		s.maxAge = d
+	}
+}
+
+// WithCredentials sets the username and password used to log in
+// when the session's token expires.
+func WithCredentials(user, pass string) Option {
+	return func(s *Session) {
+		s.user = user
+		s.pass = pass
+	}
+}
+
+// WithResponseHook sets a function called with the status and body
+// of every response from Dexcom, including errors, before it is
+// parsed. A nil hook is ignored.
+func WithResponseHook(hook func(status int, body []byte)) Option {
+	return func(s *Session) {
+		s.responseHook = hook
+	}
+}
+
+// WithMaxBackfill limits how far back Stream reaches for entries
+// when it begins: entries older than d are skipped.
+func WithMaxBackfill(d time.Duration) Option {
+	return func(s *Session) {
+		s.maxBackfill = d
+	}
+}
+
+// WithIdleConnTimeout sets how long the session keeps idle
+// connections to Dexcom open for reuse. Streams poll every five
+// minutes, so a longer timeout avoids a new TLS handshake per poll.
+// It is ignored if the session is given WithHTTPClient.
+func WithIdleConnTimeout(d time.Duration) Option {
+	return func(s *Session) {
+		s.idleConnTimeout = d
+	}
+}
+
+// WithProxy makes the session reach Dexcom through the proxy at u,
+// which may be an HTTP, HTTPS or SOCKS5 URL. It is ignored if the
+// session is given WithHTTPClient.
+func WithProxy(u *url.URL) Option {
+	return func(s *Session) {
+		s.proxy = u
+	}
+}
+
+// WithHTTPClient makes the session use c for all requests to
+// Dexcom, overriding WithIdleConnTimeout and WithProxy.
+func WithHTTPClient(c *http.Client) Option {
+	return func(s *Session) {
+		s.client = c
+	}
+}
+
+func (s *Session) httpClient() *http.Client {
+	if s.client == nil {
+		return &client
+	}
+	return s.client
+}
+
+// WithSessionPath sets the file in which Dial saves and restores
+// the session's token.
+func WithSessionPath(path string) Option {
+	return func(s *Session) {
+		s.path = path
+	}
+}
+
+// WithStateKey makes Dial sign the session file with an HMAC keyed
+// by key, and treat a file whose signature is missing or wrong as
+// corrupt, logging in afresh.
+func WithStateKey(key []byte) Option {
+	return func(s *Session) {
+		s.stateKey = key
+	}
+}
+
+// WithValidateOnDial makes Dial check a restored token with Ping,
+// logging in again if the check fails.
+func WithValidateOnDial() Option {
+	return func(s *Session) {
+		s.validate = true
+	}
+}
+
+// WithRegionFailover makes a session whose login fails in its
+// region, US by default, try the other, outside the US, and keep to
+// whichever succeeds. Each login tries each region at most once, so
+// a session does not flap between them. Only the default
+// Authenticator fails over.
+func WithRegionFailover() Option {
+	return func(s *Session) {
+		s.failover = true
+	}
+}
+
+// WithMaxEntries bounds the number of entries a single Tail parses
+// and returns to the latest n, protecting the session against
+// oversized responses. By default, Tail returns at most 10000.
+func WithMaxEntries(n int) Option {
+	return func(s *Session) {
+		s.maxEntries = n
+	}
+}
+
+// WithDropInvalid makes Tail, and so Stream, skip entries that are
+// not Valid, logging them.
+func WithDropInvalid() Option {
+	return func(s *Session) {
+		s.dropInvalid = true
+	}
+}
+
+// WithoutCache makes Dial neither restore nor save the session's
+// token, so that it logs in every time and keeps the token only in
+// memory.
+func WithoutCache() Option {
+	return WithSessionPath("")
+}
@@ -0,0 +1,64 @@
+package dex
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Tail from a TLS server standing in for Dexcom, reusing
+// connections if keepAlive is set.
+func benchmarkTailTLS(b *testing.B, keepAlive bool) {
+	f := &fakeDexcom{query: serveEntries(readings(100, 110, 120))}
+	srv := httptest.NewTLSServer(f)
+	defer srv.Close()
+
+	t := serverTransport(srv)
+	t.DisableKeepAlives = !keepAlive
+	defer t.CloseIdleConnections()
+	s, _ := f.dial(b, testToken(1), WithHTTPClient(&http.Client{Transport: t}))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := s.Tail(time.Hour); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTailKeepAlive(b *testing.B)      { benchmarkTailTLS(b, true) }
+func BenchmarkTailNewConnections(b *testing.B) { benchmarkTailTLS(b, false) }
+
+func TestProxy(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		connects []string
+	)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		connects = append(connects, r.Method+" "+r.Host)
+		mu.Unlock()
+		http.Error(w, "no tunnels here", http.StatusForbidden)
+	}))
+	defer proxy.Close()
+	u, err := url.Parse(proxy.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := DialWithToken(testToken(1), WithProxy(u), WithLogger(&testLog{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Tail(time.Hour); err == nil {
+		t.Error("Tail succeeded through a refusing proxy")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if want := "CONNECT " + usHost + ":443"; len(connects) != 1 || connects[0] != want {
+		t.Errorf("proxy got %v, want [%s]", connects, want)
+	}
+}
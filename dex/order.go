@@ -0,0 +1,28 @@
+package dex
+
+// Order is the order of the entries returned by Tail.
+type Order int
+
+const (
+	OrderAscending  Order = iota // Oldest first; the default.
+	OrderDescending              // Newest first.
+)
+
+// WithOrder sets the order of the entries returned by Tail,
+// TailContext and TailWithMeta. Stream always emits entries oldest
+// first.
+func WithOrder(o Order) Option {
+	return func(s *Session) {
+		s.order = o
+	}
+}
+
+// Put ascending entries in order o.
+func (o Order) apply(entries []Entry) []Entry {
+	if o == OrderDescending {
+		for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+			entries[i], entries[j] = entries[j], entries[i]
+		}
+	}
+	return entries
+}
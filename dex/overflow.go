@@ -0,0 +1,135 @@
+package dex
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Overflow is the policy applied by Stream when its output channel
+// is full.
+type Overflow int
+
+const (
+	Block      Overflow = iota // Wait for the consumer; the default.
+	DropOldest                 // Discard the oldest undelivered entry.
+	DropNewest                 // Discard the entry being written.
+)
+
+// WithOverflow sets the policy Stream applies when its consumer
+// falls behind. Under the drop policies, Stream keeps polling and
+// queues up to cap(out) entries (at least one) beyond those
+// buffered in out; entries dropped are counted by Dropped.
+func WithOverflow(policy Overflow) Option {
+	return func(s *Session) {
+		s.overflow = policy
+	}
+}
+
+// Dropped returns the number of entries discarded by Stream
+// because of the session's overflow policy.
+func (s *Session) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// An outbox delivers entries to a stream's consumer according to
+// an overflow policy.
+type outbox struct {
+	out     chan<- Entry
+	policy  Overflow
+	pending []Entry
+	dropped *uint64
+	seq     uint64                               // The last sequence number assigned.
+	after   func(time.Duration) <-chan time.Time // Nil means a timer.
+}
+
+// Push e to the consumer, numbering it, returning false if ctx is
+// done before e could be delivered or queued.
+func (o *outbox) push(ctx context.Context, e Entry) bool {
+	o.seq++
+	e.Seq = o.seq
+	if o.policy == Block {
+		select {
+		case o.out <- e:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	o.flush()
+	limit := cap(o.out)
+	if limit < 1 {
+		limit = 1
+	}
+	if len(o.pending) >= limit {
+		atomic.AddUint64(o.dropped, 1)
+		if o.policy == DropNewest {
+			return true
+		}
+		o.pending = o.pending[1:]
+	}
+	o.pending = append(o.pending, e)
+	o.flush()
+
+	return true
+}
+
+// Deliver pending entries without blocking.
+func (o *outbox) flush() {
+	for len(o.pending) > 0 {
+		select {
+		case o.out <- o.pending[0]:
+			o.pending = o.pending[1:]
+		default:
+			return
+		}
+	}
+}
+
+// Deliver pending entries, blocking until they are delivered or
+// ctx is done.
+func (o *outbox) drain(ctx context.Context) {
+	for len(o.pending) > 0 {
+		select {
+		case o.out <- o.pending[0]:
+			o.pending = o.pending[1:]
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Wait for d, delivering pending entries meanwhile. Wait returns
+// false if ctx is done first.
+func (o *outbox) wait(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		o.flush()
+		return ctx.Err() == nil
+	}
+	var fired <-chan time.Time
+	if o.after != nil {
+		fired = o.after(d)
+	} else {
+		t := time.NewTimer(d)
+		defer t.Stop()
+		fired = t.C
+	}
+	for {
+		var (
+			out  chan<- Entry
+			next Entry
+		)
+		if len(o.pending) > 0 {
+			out, next = o.out, o.pending[0]
+		}
+		select {
+		case out <- next:
+			o.pending = o.pending[1:]
+		case <-fired:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
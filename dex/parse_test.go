@@ -0,0 +1,144 @@
+package dex
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Tail the entries in body, a Dexcom response.
+func tailBody(t *testing.T, body string, opts ...Option) ([]Entry, *testLog) {
+	t.Helper()
+	f := &fakeDexcom{}
+	f.query = func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, body)
+	}
+	s, log := f.dial(t, testToken(1), opts...)
+	entries, err := s.Tail(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries, log
+}
+
+// A Dexcom date for t.
+func dexcomDate(t time.Time) string {
+	return fmt.Sprintf("Date(%d)", t.UnixNano()/int64(time.Millisecond))
+}
+
+func TestTailSkew(t *testing.T) {
+	wt := time.Now().Add(-10 * time.Minute).Truncate(time.Second)
+	for _, skew := range []time.Duration{0, 90 * time.Second, -7 * time.Minute} {
+		st := wt.Add(skew)
+		body := fmt.Sprintf(`[{"WT":%q,"ST":%q,"DT":%q,"Value":100,"Trend":"Flat"}]`,
+			dexcomDate(wt), dexcomDate(st), dexcomDate(wt))
+		got, _ := tailBody(t, body)
+		if len(got) != 1 {
+			t.Fatalf("got %d entries, want 1", len(got))
+		}
+		e := got[0]
+		if !e.Time.Equal(wt) {
+			t.Errorf("skew %v: time %v, want the device's %v", skew, e.Time, wt)
+		}
+		if e.Skew != skew {
+			t.Errorf("skew %v: got skew %v", skew, e.Skew)
+		}
+		if corrected := e.Time.Add(e.Skew); !corrected.Equal(st) {
+			t.Errorf("skew %v: corrected time %v, want the server's %v", skew, corrected, st)
+		}
+	}
+}
+
+// A Dexcom entry at wt with the given Trend and Value, and any extra
+// fields.
+func dexcomEntry(wt time.Time, trend, value string, extra ...string) string {
+	fields := []string{
+		fmt.Sprintf(`"WT":%q,"ST":%q,"DT":%q`, dexcomDate(wt), dexcomDate(wt), dexcomDate(wt)),
+		`"Trend":` + trend,
+		`"Value":` + value,
+	}
+	return "{" + strings.Join(append(fields, extra...), ",") + "}"
+}
+
+func TestTailTrend(t *testing.T) {
+	wt := time.Now().Add(-time.Minute).Truncate(time.Second)
+	for _, c := range []struct {
+		trend string
+		want  Dir
+	}{
+		{`4`, Flat},
+		{`1`, DoubleUp},
+		{`7`, DoubleDown},
+		{`0`, None},
+		{`"Flat"`, Flat},
+		{`"FortyFiveUp"`, FortyFiveUp},
+		{`"SingleDown"`, SingleDown},
+	} {
+		got, _ := tailBody(t, "["+dexcomEntry(wt, c.trend, "100")+"]")
+		if len(got) != 1 {
+			t.Errorf("Trend %s: got %d entries, want 1", c.trend, len(got))
+			continue
+		}
+		if got[0].Dir != c.want {
+			t.Errorf("Trend %s: got %v, want %v", c.trend, got[0].Dir, c.want)
+		}
+	}
+}
+
+func TestTailNoise(t *testing.T) {
+	wt := time.Now().Add(-10 * time.Minute).Truncate(time.Second)
+	body := "[" + dexcomEntry(wt.Add(5*time.Minute), `"Flat"`, "110") + "," +
+		dexcomEntry(wt, `"Flat"`, "100", `"Noise":3`) + "]"
+	got, _ := tailBody(t, body)
+	if len(got) != 2 {
+		t.Fatalf("got %d entries, want 2", len(got))
+	}
+	if got[0].Noise != 3 {
+		t.Errorf("with Noise 3: got noise %d", got[0].Noise)
+	}
+	if got[1].Noise != 0 {
+		t.Errorf("without Noise: got noise %d, want 0", got[1].Noise)
+	}
+}
+
+func TestTailMilliseconds(t *testing.T) {
+	wt := time.Now().Add(-time.Minute).Truncate(time.Second).Add(250 * time.Millisecond)
+	body := "[" + dexcomEntry(wt.Add(3*time.Millisecond), `"Flat"`, "101") + "," +
+		dexcomEntry(wt, `"Flat"`, "100") + "]"
+	got, _ := tailBody(t, body)
+	if len(got) != 2 {
+		t.Fatalf("got %d entries, want 2", len(got))
+	}
+	if !got[0].Time.Equal(wt) {
+		t.Errorf("got time %v, want %v", got[0].Time, wt)
+	}
+	if d := got[1].Time.Sub(got[0].Time); d != 3*time.Millisecond {
+		t.Errorf("entries are %v apart, want 3ms", d)
+	}
+}
+
+func TestTailValue(t *testing.T) {
+	wt := time.Now().Add(-time.Minute).Truncate(time.Second)
+	for _, c := range []struct {
+		value string
+		want  int
+	}{
+		{`120`, 120},
+		{`120.0`, 120},
+		{`120.4`, 120},
+		{`120.5`, 121},
+		{`"120"`, 120},
+		{`"119.6"`, 120},
+	} {
+		got, _ := tailBody(t, "["+dexcomEntry(wt, `"Flat"`, c.value)+"]")
+		if len(got) != 1 {
+			t.Errorf("Value %s: got %d entries, want 1", c.value, len(got))
+			continue
+		}
+		if got[0].Value != c.want {
+			t.Errorf("Value %s: got %d, want %d", c.value, got[0].Value, c.want)
+		}
+	}
+}
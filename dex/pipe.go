@@ -0,0 +1,50 @@
+package dex
+
+import "time"
+
+// A Stage processes one entry of a pipeline, passing on to emit any
+// number of entries: none to drop it, a modified copy to transform
+// it, or several. A stage may keep state between entries, so each
+// should be used in only one pipeline.
+type Stage func(e Entry, emit func(Entry))
+
+// Pipe returns a channel of the entries from in, passed through each
+// of stages in turn. The stages run on a single goroutine. The
+// returned channel is closed when in is.
+func Pipe(in <-chan Entry, stages ...Stage) <-chan Entry {
+	out := make(chan Entry)
+	emit := func(e Entry) { out <- e }
+	for i := len(stages) - 1; i >= 0; i-- {
+		stage, next := stages[i], emit
+		emit = func(e Entry) { stage(e, next) }
+	}
+
+	go func() {
+		defer close(out)
+		for e := range in {
+			emit(e)
+		}
+	}()
+	return out
+}
+
+// FreshStage is a Stage that drops entries older than maxAge.
+func FreshStage(maxAge time.Duration) Stage {
+	return func(e Entry, emit func(Entry)) {
+		if time.Since(e.Time) <= maxAge {
+			emit(e)
+		}
+	}
+}
+
+// DedupStage is a Stage that drops entries no later than the last
+// one it passed on, such as those repeated by overlapping tails.
+func DedupStage() Stage {
+	var last time.Time
+	return func(e Entry, emit func(Entry)) {
+		if e.Time.After(last) {
+			last = e.Time
+			emit(e)
+		}
+	}
+}
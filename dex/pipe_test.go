@@ -0,0 +1,62 @@
+package dex
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestPipe(t *testing.T) {
+	now := time.Now()
+	at := func(ago time.Duration, v int) Entry {
+		return Entry{Time: now.Add(-ago), Value: v}
+	}
+	in := make(chan Entry)
+	go func() {
+		defer close(in)
+		for _, e := range []Entry{
+			at(3*time.Hour, 90), // Stale.
+			at(20*time.Minute, 100),
+			at(15*time.Minute, 110),
+			at(20*time.Minute, 100), // Repeated.
+			at(10*time.Minute, 120),
+			at(10*time.Minute, 120), // Repeated.
+			at(5*time.Minute, 130),
+		} {
+			in <- e
+		}
+	}()
+	// A stage that passes on each entry twice, the second 1 mg/dL
+	// higher a minute later, and one that drops entries above 125.
+	double := func(e Entry, emit func(Entry)) {
+		emit(e)
+		e.Time = e.Time.Add(time.Minute)
+		e.Value++
+		emit(e)
+	}
+	max := func(e Entry, emit func(Entry)) {
+		if e.Value <= 125 {
+			emit(e)
+		}
+	}
+
+	var got []int
+	for e := range Pipe(in, FreshStage(time.Hour), DedupStage(), double, max) {
+		got = append(got, e.Value)
+	}
+	if want := []int{100, 101, 110, 111, 120, 121}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// With no stages, Pipe passes entries through.
+	in = make(chan Entry, 1)
+	in <- at(0, 100)
+	close(in)
+	n := 0
+	for range Pipe(in) {
+		n++
+	}
+	if n != 1 {
+		t.Errorf("got %d entries through an empty pipe, want 1", n)
+	}
+}
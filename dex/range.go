@@ -0,0 +1,88 @@
+package dex
+
+// A Range is a clinical zone of blood glucose.
+type Range int
+
+const (
+	UrgentLow Range = iota
+	Low
+	InRange
+	High
+	VeryHigh
+)
+
+// Ranges holds the cutoffs, in mg/dL, between zones. A value below
+// UrgentLow is UrgentLow; below Low is Low; above VeryHigh is
+// VeryHigh; above High is High; and otherwise InRange.
+type Ranges struct {
+	UrgentLow, Low, High, VeryHigh int
+}
+
+// DefaultRanges are the customary cutoffs.
+var DefaultRanges = Ranges{UrgentLow: 55, Low: 70, High: 180, VeryHigh: 250}
+
+// Classify returns the zone of the blood glucose value v.
+func (r Ranges) Classify(v int) Range {
+	switch {
+	case v < r.UrgentLow:
+		return UrgentLow
+	case v < r.Low:
+		return Low
+	case v > r.VeryHigh:
+		return VeryHigh
+	case v > r.High:
+		return High
+	default:
+		return InRange
+	}
+}
+
+// Range returns the zone of the entry's value, given the bounds of
+// the target range; zero bounds take their defaults. The urgent low
+// and very high cutoffs are those of DefaultRanges.
+func (e Entry) Range(lo, hi int) Range {
+	r := DefaultRanges
+	if lo != 0 {
+		r.Low = lo
+	}
+	if hi != 0 {
+		r.High = hi
+	}
+	return r.Classify(e.Value)
+}
+
+func (r Range) String() string {
+	switch r {
+	case UrgentLow:
+		return "UrgentLow"
+	case Low:
+		return "Low"
+	case InRange:
+		return "InRange"
+	case High:
+		return "High"
+	case VeryHigh:
+		return "VeryHigh"
+	default:
+		return "Range(?)"
+	}
+}
+
+// Color returns a conventional display color for the zone, as a
+// CSS hex color.
+func (r Range) Color() string {
+	switch r {
+	case UrgentLow:
+		return "#b71c1c"
+	case Low:
+		return "#e53935"
+	case InRange:
+		return "#43a047"
+	case High:
+		return "#fdd835"
+	case VeryHigh:
+		return "#fb8c00"
+	default:
+		return "#9e9e9e"
+	}
+}
@@ -0,0 +1,91 @@
+package dex
+
+import (
+	"context"
+	"time"
+)
+
+// Bounds on the backoff between StreamRetry's reconnects.
+const (
+	minReconnectWait = 5 * time.Second
+	maxReconnectWait = 5 * time.Minute
+)
+
+// WithReconnectHook sets a function that StreamRetry calls before
+// each reconnect, with the number of consecutive failures and the
+// time it will wait before reconnecting.
+func WithReconnectHook(hook func(attempt int, wait time.Duration)) Option {
+	return func(s *Session) {
+		s.reconnectHook = hook
+	}
+}
+
+// StreamRetry is like StreamContext, but rather than giving up when
+// the stream fails, it backs off and reconnects, resuming after
+// the last entry written to out. It closes out only once ctx is
+// done, after passing on any entries drained as the stream is
+// cancelled.
+func StreamRetry(ctx context.Context, s *Session, begin time.Time, out chan<- Entry) {
+	defer close(out)
+
+	attempt := 0
+	emitLatest := s.emitLatest
+	for {
+		in := make(chan Entry)
+		go s.stream(ctx, begin, emitLatest, in)
+		// The latest entry is emitted only by the first stream.
+		emitLatest = false
+		for e := range in {
+			select {
+			case out <- e:
+			case <-ctx.Done():
+				s.forwardDrained(in, out)
+				return
+			}
+			// The latest entry, if emitted, may precede begin.
+			if e.Time.After(begin) {
+				begin = e.Time
+			}
+			attempt = 0
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		attempt++
+		wait := maxReconnectWait
+		if attempt < 10 && minReconnectWait<<uint(attempt-1) < wait {
+			wait = minReconnectWait << uint(attempt-1)
+		}
+		s.event(BackoffEvent{Attempt: attempt, Wait: wait, Reason: "stream failure"})
+		if s.reconnectHook != nil {
+			s.reconnectHook(attempt, wait)
+		}
+		if !s.sleep(ctx, wait) {
+			return
+		}
+	}
+}
+
+// Let the stream in wind down, passing on to out the entries it
+// drains on cancellation, for as long as a drain may take.
+func (s *Session) forwardDrained(in <-chan Entry, out chan<- Entry) {
+	if s.noDrain {
+		for range in {
+		}
+		return
+	}
+
+	t := time.NewTimer(drainTimeout)
+	defer t.Stop()
+	for e := range in {
+		select {
+		case out <- e:
+		case <-t.C:
+			for range in {
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package dex
+
+import "sync"
+
+// A Ring holds the most recent entries added to it, up to a fixed
+// capacity. It is safe for concurrent use.
+type Ring struct {
+	mu    sync.Mutex
+	buf   []Entry
+	start int // Index of the oldest entry.
+	n     int // Number of entries held.
+}
+
+// NewRing returns a ring holding at most capacity entries.
+func NewRing(capacity int) *Ring {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &Ring{buf: make([]Entry, capacity)}
+}
+
+// Add e to the ring, evicting the oldest entry if the ring is full.
+func (r *Ring) Add(e Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.n < len(r.buf) {
+		r.buf[(r.start+r.n)%len(r.buf)] = e
+		r.n++
+		return
+	}
+	r.buf[r.start] = e
+	r.start = (r.start + 1) % len(r.buf)
+}
+
+// Slice returns a copy of the ring's entries, oldest first.
+func (r *Ring) Slice() []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]Entry, r.n)
+	for i := range out {
+		out[i] = r.buf[(r.start+i)%len(r.buf)]
+	}
+	return out
+}
+
+// Len returns the number of entries in the ring.
+func (r *Ring) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.n
+}
+
+// Cap returns the capacity of the ring.
+func (r *Ring) Cap() int {
+	return len(r.buf)
+}
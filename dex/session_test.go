@@ -0,0 +1,353 @@
+package dex
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Dial user with f, saving the session at path.
+func (f *fakeDexcom) dialUser(t testing.TB, user, path string, opts ...Option) (*Session, *testLog, error) {
+	t.Helper()
+	log := &testLog{}
+	opts = append([]Option{
+		WithSessionPath(path),
+		WithHTTPClient(f.client()),
+		WithLogger(log),
+	}, opts...)
+	s, err := Dial(user, "pass", opts...)
+	return s, log, err
+}
+
+func TestDialSetsAsideCorruptSession(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session")
+	const corrupt = `{"token": "0000`
+	if err := ioutil.WriteFile(path, []byte(corrupt), 0600); err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeDexcom{}
+	s, log, err := f.dialUser(t, "user", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if logins, _ := f.counts(); logins != 1 {
+		t.Errorf("logged in %d times, want 1", logins)
+	}
+	if !log.contains("Corrupt session file") {
+		t.Error("corrupt session file not logged")
+	}
+	if got := s.restore(path); got != testToken(1) {
+		t.Errorf("saved token is %q, want %s", got, testToken(1))
+	}
+	b, err := ioutil.ReadFile(path + ".corrupt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != corrupt {
+		t.Errorf("set aside %q, want %q", b, corrupt)
+	}
+}
+
+func TestDialValidatesRestoredToken(t *testing.T) {
+	for _, validate := range []bool{false, true} {
+		path := filepath.Join(t.TempDir(), "session")
+		stale := fmt.Sprintf("{\"token\": %q}\n", testToken(9))
+		if err := ioutil.WriteFile(path, []byte(stale), 0600); err != nil {
+			t.Fatal(err)
+		}
+		f := &fakeDexcom{}
+		f.query = func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("sessionID") == testToken(9) {
+				w.WriteHeader(http.StatusInternalServerError)
+				fmt.Fprint(w, `{"Code":"SessionIdNotFound"}`)
+				return
+			}
+			fmt.Fprint(w, "[]")
+		}
+		var opts []Option
+		if validate {
+			opts = append(opts, WithValidateOnDial())
+		}
+		s, log, err := f.dialUser(t, "user", path, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want, logins := testToken(9), 0
+		if validate {
+			want, logins = testToken(1), 1
+			if !log.contains("Restored session is not valid") {
+				t.Error("invalid session not logged")
+			}
+		}
+		if got := s.getToken(); got != want {
+			t.Errorf("validate=%v: token is %s, want %s", validate, got, want)
+		}
+		if got, _ := f.counts(); got != logins {
+			t.Errorf("validate=%v: logged in %d times, want %d", validate, got, logins)
+		}
+		if got := s.restore(path); got != want {
+			t.Errorf("validate=%v: saved token is %s, want %s", validate, got, want)
+		}
+	}
+}
+
+func TestLoginWithoutToken(t *testing.T) {
+	for _, body := range []string{`""`, `"00000000-0000-0000-0000-000000000000"`, `"not a token"`} {
+		f := &fakeDexcom{}
+		f.login = func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, body)
+		}
+		_, _, err := f.dialUser(t, "user", filepath.Join(t.TempDir(), "session"))
+		if err != ErrAuth {
+			t.Errorf("%s: Dial returned %v, want %v", body, err, ErrAuth)
+		}
+
+		s, _ := f.dial(t, testToken(1))
+		if err := s.Refresh(); err != ErrAuth {
+			t.Errorf("%s: Refresh returned %v, want %v", body, err, ErrAuth)
+		}
+		if got := s.getToken(); got != testToken(1) {
+			t.Errorf("%s: token is %q after a failed login, want %s", body, got, testToken(1))
+		}
+	}
+}
+
+func TestSessionPath(t *testing.T) {
+	t.Setenv("HOME", "/home/u")
+	users := []string{"user", "User", "a/b@example.com", "../../etc/passwd", "ünïcode user\x00"}
+	seen := make(map[string]string)
+	for _, user := range users {
+		path := sessionPath(user)
+		if dir := filepath.Dir(path); dir != "/home/u" {
+			t.Errorf("%q: session file in %s, want /home/u", user, dir)
+		}
+		if base := filepath.Base(path); !strings.HasPrefix(base, ".dex.") || strings.Trim(base[len(".dex."):], "0123456789abcdef") != "" {
+			t.Errorf("%q: session file named %s", user, base)
+		}
+		if other, ok := seen[path]; ok {
+			t.Errorf("%q and %q share session file %s", user, other, path)
+		}
+		seen[path] = user
+	}
+}
+
+func TestDialMigratesLegacySession(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	legacy := filepath.Join(home, ".dex.user@example.com")
+	saved := fmt.Sprintf("{\"token\": %q}\n", testToken(7))
+	if err := ioutil.WriteFile(legacy, []byte(saved), 0600); err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeDexcom{}
+	s, err := Dial("user@example.com", "pass", WithHTTPClient(f.client()), WithLogger(&testLog{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := s.getToken(); got != testToken(7) {
+		t.Errorf("token is %s, want %s", got, testToken(7))
+	}
+	if logins, _ := f.counts(); logins != 0 {
+		t.Errorf("logged in %d times, want 0", logins)
+	}
+	if got := s.restore(sessionPath("user@example.com")); got != testToken(7) {
+		t.Errorf("migrated token is %q, want %s", got, testToken(7))
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Errorf("legacy session file remains: %v", err)
+	}
+}
+
+func TestDialWithoutCache(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	f := &fakeDexcom{}
+	for i := 1; i <= 2; i++ {
+		s, err := Dial("user", "pass", WithoutCache(), WithHTTPClient(f.client()), WithLogger(&testLog{}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Refresh(); err != nil {
+			t.Fatal(err)
+		}
+		// Each Dial logs in anew, and each Refresh again.
+		if logins, _ := f.counts(); logins != 2*i {
+			t.Errorf("dial %d: logged in %d times, want %d", i, logins, 2*i)
+		}
+	}
+
+	files, err := ioutil.ReadDir(home)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fi := range files {
+		t.Errorf("session file %s was created", fi.Name())
+	}
+}
+
+func TestRegionFailover(t *testing.T) {
+	for _, failover := range []bool{false, true} {
+		f := &fakeDexcom{}
+		f.login = func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Host == usHost {
+				w.WriteHeader(http.StatusForbidden)
+				fmt.Fprint(w, `{"Code":"AccountPasswordInvalid"}`)
+				return
+			}
+			fmt.Fprintf(w, "%q", testToken(1))
+		}
+		var opts []Option
+		if failover {
+			opts = append(opts, WithRegionFailover())
+		}
+		s, _, err := f.dialUser(t, "user", filepath.Join(t.TempDir(), "session"), opts...)
+		if !failover {
+			if err == nil {
+				t.Error("logged in without failing over")
+			}
+			if want := []string{usHost}; strings.Join(f.hosts, " ") != strings.Join(want, " ") {
+				t.Errorf("requested hosts %v, want %v", f.hosts, want)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The session keeps to the region that succeeded.
+		if _, err := s.Tail(time.Hour); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Refresh(); err != nil {
+			t.Fatal(err)
+		}
+		want := []string{usHost, ousHost, ousHost, ousHost}
+		if strings.Join(f.hosts, " ") != strings.Join(want, " ") {
+			t.Errorf("requested hosts %v, want %v", f.hosts, want)
+		}
+	}
+}
+
+func TestRegionFailoverBothFail(t *testing.T) {
+	f := &fakeDexcom{}
+	f.login = func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}
+	_, _, err := f.dialUser(t, "user", filepath.Join(t.TempDir(), "session"), WithRegionFailover())
+	if err == nil {
+		t.Fatal("logged in with no region accepting")
+	}
+	// Each region is tried once.
+	if want := []string{usHost, ousHost}; strings.Join(f.hosts, " ") != strings.Join(want, " ") {
+		t.Errorf("requested hosts %v, want %v", f.hosts, want)
+	}
+}
+
+// A fakeAuth issues numbered tokens.
+type fakeAuth struct {
+	calls int
+}
+
+func (a *fakeAuth) Authenticate(ctx context.Context) (string, error) {
+	a.calls++
+	return testToken(100 + a.calls), nil
+}
+
+func TestAuthenticator(t *testing.T) {
+	entries := readings(100, 110)
+	f := &fakeDexcom{}
+	f.query = func(w http.ResponseWriter, r *http.Request) {
+		// The first token expires on its first use.
+		if r.URL.Query().Get("sessionID") == testToken(101) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		serveEntries(entries)(w, r)
+	}
+	auth := &fakeAuth{}
+	s, _, err := f.dialUser(t, "user", filepath.Join(t.TempDir(), "session"), WithAuthenticator(auth))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.getToken(); got != testToken(101) {
+		t.Errorf("dialed with token %s, want %s", got, testToken(101))
+	}
+
+	got, err := s.Tail(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Errorf("got %d entries, want 2", len(got))
+	}
+	if auth.calls != 2 {
+		t.Errorf("authenticated %d times, want 2", auth.calls)
+	}
+	if logins, _ := f.counts(); logins != 0 {
+		t.Errorf("logged in to Dexcom %d times, want 0", logins)
+	}
+	if want := []string{testToken(101), testToken(102)}; strings.Join(f.sessions, " ") != strings.Join(want, " ") {
+		t.Errorf("queried with sessions %v, want %v", f.sessions, want)
+	}
+}
+
+func TestStateKey(t *testing.T) {
+	key := []byte("secret")
+	signed := func(token string) string {
+		s := &Session{stateKey: key}
+		return fmt.Sprintf("{\"token\": %q, \"mac\": %q}\n", token, s.mac(token))
+	}
+	for _, c := range []struct {
+		name    string
+		saved   string
+		key     []byte
+		restore bool
+	}{
+		{"valid", signed(testToken(7)), key, true},
+		{"tampered", strings.Replace(signed(testToken(7)), testToken(7), testToken(8), 1), key, false},
+		{"missing", fmt.Sprintf("{\"token\": %q}\n", testToken(7)), key, false},
+		{"other key", signed(testToken(7)), []byte("other"), false},
+		// Without a key, the MAC is not checked.
+		{"no key", signed(testToken(7)), nil, true},
+	} {
+		path := filepath.Join(t.TempDir(), "session")
+		if err := ioutil.WriteFile(path, []byte(c.saved), 0600); err != nil {
+			t.Fatal(err)
+		}
+		f := &fakeDexcom{}
+		s, log, err := f.dialUser(t, "user", path, WithStateKey(c.key))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want, logins := testToken(7), 0
+		if !c.restore {
+			want, logins = testToken(1), 1
+			if !log.contains("MAC mismatch") {
+				t.Errorf("%s: mismatch not logged", c.name)
+			}
+			if _, err := os.Stat(path + ".corrupt"); err != nil {
+				t.Errorf("%s: file not set aside: %v", c.name, err)
+			}
+		}
+		if got := s.getToken(); got != want {
+			t.Errorf("%s: token is %s, want %s", c.name, got, want)
+		}
+		if got, _ := f.counts(); got != logins {
+			t.Errorf("%s: logged in %d times, want %d", c.name, got, logins)
+		}
+		// What Dial saves, it restores.
+		if got := s.restore(path); got != want {
+			t.Errorf("%s: saved token is %q, want %s", c.name, got, want)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package dex
+
+import (
+	"context"
+	"time"
+)
+
+// A Source streams entries, as does a Session. StreamContext
+// writes entries newer than begin to out, closing out when the
+// stream ends or ctx is done.
+type Source interface {
+	StreamContext(ctx context.Context, begin time.Time, out chan<- Entry)
+}
+
+// Discard, in the background, the entries a stream sends to ch as it
+// winds down, such as those it drains on cancellation, so that it
+// need not wait on a consumer that has stopped reading.
+func discard(ch <-chan Entry) {
+	go func() {
+		for range ch {
+		}
+	}()
+}
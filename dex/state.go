@@ -0,0 +1,68 @@
+package dex
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"time"
+)
+
+// How far back ResumeStream reaches, absent WithMaxBackfill.
+const maxResume = 24 * time.Hour
+
+// StreamState is the position of a stream, as saved to the path
+// given WithStreamStatePath.
+type StreamState struct {
+	Last time.Time `json:"last"` // The time of the last entry emitted.
+}
+
+// WithStreamStatePath makes Stream save its position to path after
+// each batch of entries, so that ResumeStream can pick up where it
+// left off.
+func WithStreamStatePath(path string) Option {
+	return func(s *Session) {
+		s.statePath = path
+	}
+}
+
+// ResumeStream is like StreamContext, but begins after the last
+// entry emitted by a previous stream, as saved at the session's
+// stream state path. If there is no saved position, or it is older
+// than the session's maximum backfill (by default, a day), the stream
+// begins that long ago. A resumed stream does not emit the latest
+// entry again WithEmitLatestOnStart.
+func (s *Session) ResumeStream(ctx context.Context, out chan<- Entry) {
+	limit := maxResume
+	if s.maxBackfill > 0 {
+		limit = s.maxBackfill
+	}
+	begin := time.Now().Add(-limit)
+	emitLatest := s.emitLatest
+	if state, err := s.loadState(); err == nil && state.Last.After(begin) {
+		begin, emitLatest = state.Last, false
+	}
+	s.stream(ctx, begin, emitLatest, out)
+}
+
+func (s *Session) loadState() (StreamState, error) {
+	var state StreamState
+	b, err := ioutil.ReadFile(s.statePath)
+	if err != nil {
+		return state, err
+	}
+	err = json.Unmarshal(b, &state)
+	return state, err
+}
+
+func (s *Session) saveState(state StreamState) {
+	if s.statePath == "" {
+		return
+	}
+	b, err := json.Marshal(state)
+	if err == nil {
+		err = ioutil.WriteFile(s.statePath, b, 0600)
+	}
+	if err != nil {
+		s.logf("Failed to save stream state: %v\n", err)
+	}
+}
@@ -0,0 +1,249 @@
+package dex
+
+import (
+	"context"
+	"time"
+)
+
+// Skew beyond which Stream warns that the device clock is off.
+const maxSkew = 5 * time.Minute
+
+// The interval between entries beyond which Stream reports a gap.
+const maxGap = 10 * time.Minute
+
+// How long a cancelled stream may spend delivering its last entries.
+const drainTimeout = 10 * time.Second
+
+// The defaults for WithPenaltyCap and WithPenaltyStep.
+const (
+	defaultPenaltyCap  = 10 * time.Second
+	defaultPenaltyStep = time.Second
+)
+
+// WithPenaltyCap sets the longest delay Stream adds between polls
+// while awaiting an entry that is late. The default is 10 seconds.
+func WithPenaltyCap(d time.Duration) Option {
+	return func(s *Session) {
+		s.penaltyCap = d
+	}
+}
+
+// WithPenaltyStep sets how much Stream lengthens the delay between
+// polls each time an entry it awaits has not arrived, up to the
+// penalty cap. The default is a second.
+func WithPenaltyStep(d time.Duration) Option {
+	return func(s *Session) {
+		s.penaltyStep = d
+	}
+}
+
+// WithEmitLatestOnStart makes Stream begin by emitting the latest
+// entry, even if it is not newer than begin, so that a consumer
+// streaming from now need not wait for the next reading. Streams
+// that pick up where another left off, as StreamRetry does on
+// reconnecting and ResumeStream does from a saved position, do not
+// emit it again.
+func WithEmitLatestOnStart() Option {
+	return func(s *Session) {
+		s.emitLatest = true
+	}
+}
+
+// The next delay between polls after penalty.
+func (s *Session) nextPenalty(penalty time.Duration) time.Duration {
+	limit, step := s.penaltyCap, s.penaltyStep
+	if limit <= 0 {
+		limit = defaultPenaltyCap
+	}
+	if step <= 0 {
+		step = defaultPenaltyStep
+	}
+	if penalty += step; penalty > limit {
+		penalty = limit
+	}
+	return penalty
+}
+
+// Stream entries as they become available. They are written
+// to channel out; the channel is closed on error. When out is full,
+// Stream blocks unless the session was dialed WithOverflow.
+func (s *Session) Stream(begin time.Time, out chan<- Entry) {
+	s.StreamContext(context.Background(), begin, out)
+}
+
+// StreamContext is like Stream, but also stops, closing out, when
+// ctx is done. If ctx is cancelled (rather than past its deadline),
+// StreamContext first polls Dexcom once more and delivers the
+// entries that remain, so that a clean shutdown does not drop the
+// last reading. This is brief and best effort: entries may be lost
+// if Dexcom or the consumer is slow. The consumer should keep
+// reading until out is closed, unless the session was dialed
+// WithoutDrainOnCancel.
+func (s *Session) StreamContext(ctx context.Context, begin time.Time, out chan<- Entry) {
+	s.stream(ctx, begin, s.emitLatest, out)
+}
+
+// Stream as StreamContext, beginning with the latest entry if
+// emitLatest is set.
+func (s *Session) stream(ctx context.Context, begin time.Time, emitLatest bool, out chan<- Entry) {
+	// TODO: base eta on "now" time instead of begin (?),
+	// or compute skew based on the difference between
+	// this time and wall time?
+	//
+	// Higher backoff value?
+
+	defer close(out)
+
+	if s.maxBackfill > 0 && time.Since(begin) > s.maxBackfill {
+		from := time.Now().Add(-s.maxBackfill)
+		s.logf("Skipping %v of backfill before %v\n", from.Sub(begin), from)
+		begin = from
+	}
+
+	o := &outbox{out: out, policy: s.overflow, dropped: &s.dropped, after: s.after}
+	last := begin
+	defer func() { s.drain(ctx, o, last) }()
+
+	eta := time.Now().Add(s.startupJitter())
+	sampled := false
+	polled := false
+	penalty := 0 * time.Second
+	total := 0 * time.Second
+
+	for {
+		now := time.Now()
+		if eta.After(now) {
+			wait := eta.Sub(now)
+			if !o.wait(ctx, wait) {
+				return
+			}
+		}
+		if !o.wait(ctx, penalty) {
+			return
+		}
+		total += penalty
+
+		penalty = s.nextPenalty(penalty)
+
+		// We extend our duration a little bit to give some wiggle
+		// room for uneven sampling.
+		dur := time.Since(begin) + 5*time.Minute
+		if emitLatest && !polled && dur < maxGap {
+			// Look back far enough to find the latest entry.
+			dur = maxGap
+		}
+		ents, err := s.tail(ctx, dur, nil, true)
+		if err != nil {
+			s.logf("Failed to retrieve data\n")
+			return
+		}
+
+		// Entries at or before begin are never emitted otherwise, so
+		// the latest cannot be repeated.
+		if emitLatest && !polled && len(ents) > 0 {
+			if e := ents[len(ents)-1]; !e.Time.After(begin) && !o.push(ctx, e) {
+				return
+			}
+		}
+		polled = true
+
+		var (
+			newest *Entry
+			first  time.Time
+			count  int
+		)
+		for i := range ents {
+			if ents[i].Time.After(begin) {
+				if count++; count == 1 {
+					first = ents[i].Time
+				}
+				if skew := ents[i].Skew; skew > maxSkew || skew < -maxSkew {
+					s.logf("Entry at %v skewed by %v\n", ents[i].Time, skew)
+				}
+				if !o.push(ctx, ents[i]) {
+					return
+				}
+				newest = &ents[i]
+				last = newest.Time
+				if ctx.Err() != nil {
+					return
+				}
+			}
+		}
+
+		if newest != nil {
+			// Dexcom samples every five minutes. Of course some may be
+			// missed because devices are offline, or other failures.
+			s.event(SampleEvent{Entries: count, Penalty: total})
+			if sampled && first.Sub(begin) > maxGap {
+				s.event(GapEvent{From: begin, Gap: first.Sub(begin)})
+			}
+			sampled = true
+			begin = newest.Time
+			s.saveState(StreamState{Last: begin})
+			eta = begin.Add(5 * time.Minute)
+			penalty = 0 * time.Second
+			total = 0 * time.Second
+		}
+	}
+}
+
+// WithoutDrainOnCancel makes StreamContext close its channel as soon
+// as its context is cancelled, without delivering the entries that
+// remain, for consumers that stop reading once they cancel.
+func WithoutDrainOnCancel() Option {
+	return func(s *Session) {
+		s.noDrain = true
+	}
+}
+
+// Drain delivers entries newer than begin, including those pending
+// in o, after ctx is cancelled, unless the session was dialed
+// WithoutDrainOnCancel.
+func (s *Session) drain(ctx context.Context, o *outbox, begin time.Time) {
+	if s.noDrain || ctx.Err() != context.Canceled {
+		return
+	}
+
+	dctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+
+	// Poll once, without retrying or logging in again, which would
+	// hold up the shutdown. Tail may return entries along with an
+	// error if time runs out.
+	ents, _ := s.tail(dctx, time.Since(begin)+5*time.Minute, nil, false)
+	for i := range ents {
+		if ents[i].Time.After(begin) && !o.push(dctx, ents[i]) {
+			return
+		}
+	}
+	o.drain(dctx)
+}
+
+// Sleep for d, as sleep does, by the session's after, if it has one.
+func (s *Session) sleep(ctx context.Context, d time.Duration) bool {
+	if s.after == nil || d <= 0 {
+		return sleep(ctx, d)
+	}
+	select {
+	case <-s.after(d):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Sleep for d, returning false if ctx is done first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,308 @@
+package dex
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// Serve no entries, sending the minutes of each query to minutes.
+func recordMinutes(minutes chan<- float64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		m, _ := strconv.ParseFloat(r.URL.Query().Get("minutes"), 64)
+		select {
+		case minutes <- m:
+		default:
+		}
+		w.Write([]byte("[]"))
+	}
+}
+
+func TestStreamMaxBackfill(t *testing.T) {
+	minutes := make(chan float64, 1)
+	f := &fakeDexcom{query: recordMinutes(minutes)}
+	s, log := f.dial(t, testToken(1), WithMaxBackfill(time.Hour))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := make(chan Entry)
+	go s.StreamContext(ctx, time.Now().Add(-30*24*time.Hour), out)
+
+	select {
+	case m := <-minutes:
+		if m < 60 || m > 70 {
+			t.Errorf("queried %v minutes, want about 65", m)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream did not query")
+	}
+	if !log.contains("Skipping") {
+		t.Error("skipped backfill not logged")
+	}
+	cancel()
+	for range out {
+	}
+}
+
+func TestNextPenalty(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		opts []Option
+		want []time.Duration
+	}{
+		{"default", nil, []time.Duration{
+			time.Second, 2 * time.Second, 3 * time.Second, 4 * time.Second, 5 * time.Second,
+			6 * time.Second, 7 * time.Second, 8 * time.Second, 9 * time.Second, 10 * time.Second,
+			10 * time.Second}},
+		{"cap", []Option{WithPenaltyCap(3 * time.Second)}, []time.Duration{
+			time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second}},
+		{"step", []Option{WithPenaltyStep(4 * time.Second)}, []time.Duration{
+			4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}},
+		{"both", []Option{WithPenaltyCap(time.Minute), WithPenaltyStep(25 * time.Second)}, []time.Duration{
+			25 * time.Second, 50 * time.Second, time.Minute, time.Minute}},
+		{"zero", []Option{WithPenaltyCap(0), WithPenaltyStep(-time.Second)}, []time.Duration{
+			time.Second, 2 * time.Second}},
+	} {
+		s, _ := DialWithToken(testToken(1), c.opts...)
+		var penalty time.Duration
+		for i, want := range c.want {
+			if penalty = s.nextPenalty(penalty); penalty != want {
+				t.Errorf("%s: penalty %d is %v, want %v", c.name, i, penalty, want)
+			}
+		}
+	}
+}
+
+func TestStreamEmitLatestOnStart(t *testing.T) {
+	entries := readings(100, 110)
+	next := Entry{Time: time.Now().Add(time.Minute).Truncate(time.Second), Value: 120, Dir: Flat}
+	f := &fakeDexcom{}
+	f.query = func(w http.ResponseWriter, r *http.Request) {
+		// Poll a few times before the next reading arrives.
+		if _, queries := f.counts(); queries <= 5 {
+			serveEntries(entries)(w, r)
+			return
+		}
+		serveEntries(append(entries, next))(w, r)
+	}
+	s, _ := f.dial(t, testToken(1), WithEmitLatestOnStart(), WithPenaltyStep(10*time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := make(chan Entry)
+	go s.StreamContext(ctx, time.Now(), out)
+
+	var got []int
+	for len(got) < 2 {
+		select {
+		case e := <-out:
+			got = append(got, e.Value)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("got %v, then nothing", got)
+		}
+	}
+	if got[0] != 110 || got[1] != 120 {
+		t.Errorf("got %v, want [110 120]", got)
+	}
+	if _, queries := f.counts(); queries < 6 {
+		t.Errorf("queried %d times, want at least 6", queries)
+	}
+	cancel()
+	for e := range out {
+		t.Errorf("got %v after cancelling", e)
+	}
+}
+
+func TestStreamPenalty(t *testing.T) {
+	entries := readings(100, 110)
+	begin := entries[1].Time
+	next := Entry{Time: begin.Add(time.Second), Value: 120, Dir: Flat}
+	f := &fakeDexcom{}
+	f.query = func(w http.ResponseWriter, r *http.Request) {
+		// The next reading is late, arriving on the sixth poll.
+		if _, queries := f.counts(); queries < 6 {
+			serveEntries(entries)(w, r)
+			return
+		}
+		serveEntries([]Entry{next})(w, r)
+	}
+	s, _ := f.dial(t, testToken(1), WithPenaltyCap(3*time.Second), WithPenaltyStep(time.Second))
+	// A clock on which every wait is over at once.
+	waits := make(chan time.Duration, 100)
+	s.after = func(d time.Duration) <-chan time.Time {
+		select {
+		case waits <- d:
+		default:
+		}
+		c := make(chan time.Time, 1)
+		c <- time.Now()
+		return c
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := make(chan Entry, 10)
+	go s.StreamContext(ctx, begin, out)
+
+	var got []time.Duration
+	for len(got) < 8 {
+		select {
+		case d := <-waits:
+			got = append(got, d)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("waited %v, then stopped", got)
+		}
+	}
+	cancel()
+	for range out {
+	}
+
+	// The delay grows by the step to the cap while the reading is
+	// late. Once it arrives, Stream waits for the one after, due five
+	// minutes on (as this clock stands still, on each poll), and the
+	// delay begins again from nothing.
+	want := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second, 3 * time.Second}
+	for i, d := range want {
+		if got[i] != d {
+			t.Errorf("wait %d is %v, want %v", i, got[i], d)
+		}
+	}
+	for _, d := range got[5:7] {
+		if d < 4*time.Minute || d > 5*time.Minute+time.Second {
+			t.Errorf("waited %v for the next reading, want about 5m", d)
+		}
+	}
+	if got[7] != time.Second {
+		t.Errorf("delay %v after the reading, want 1s", got[7])
+	}
+}
+
+// A clock for s on which every wait is over at once.
+func instantWaits(s *Session) {
+	s.after = func(time.Duration) <-chan time.Time {
+		c := make(chan time.Time, 1)
+		c <- time.Now()
+		return c
+	}
+}
+
+// Receive n entries from out, then cancel the stream and drain it.
+func receive(t *testing.T, out <-chan Entry, n int, cancel func()) []int {
+	t.Helper()
+	var got []int
+	for len(got) < n {
+		select {
+		case e := <-out:
+			got = append(got, e.Value)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("got %v, then nothing", got)
+		}
+	}
+	cancel()
+	for e := range out {
+		got = append(got, e.Value)
+	}
+	return got
+}
+
+func TestStreamRetryEmitsLatestOnce(t *testing.T) {
+	entries := readings(100, 110)
+	next := Entry{Time: entries[1].Time.Add(time.Second), Value: 120, Dir: Flat}
+	f := &fakeDexcom{}
+	f.query = func(w http.ResponseWriter, r *http.Request) {
+		// The next reading arrives after the stream has failed, and
+		// reconnected.
+		switch _, queries := f.counts(); {
+		case queries == 2:
+			w.Write([]byte("{}"))
+		case queries < 5:
+			serveEntries(entries)(w, r)
+		default:
+			serveEntries(append(entries, next))(w, r)
+		}
+	}
+	s, _ := f.dial(t, testToken(1), WithEmitLatestOnStart())
+	instantWaits(s)
+	reconnects := 0
+	s.reconnectHook = func(int, time.Duration) { reconnects++ }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := make(chan Entry)
+	go StreamRetry(ctx, s, entries[1].Time, out)
+	got := receive(t, out, 2, cancel)
+	if len(got) != 2 || got[0] != 110 || got[1] != 120 {
+		t.Errorf("got %v, want [110 120]", got)
+	}
+	if reconnects != 1 {
+		t.Errorf("reconnected %d times, want 1", reconnects)
+	}
+}
+
+func TestResumeStreamEmitsLatestOnce(t *testing.T) {
+	entries := readings(100, 110)
+	next := Entry{Time: entries[1].Time.Add(time.Second), Value: 120, Dir: Flat}
+	path := filepath.Join(t.TempDir(), "state")
+	f := &fakeDexcom{}
+	f.query = func(w http.ResponseWriter, r *http.Request) {
+		if _, queries := f.counts(); queries < 3 {
+			serveEntries(entries)(w, r)
+			return
+		}
+		serveEntries(append(entries, next))(w, r)
+	}
+	s, _ := f.dial(t, testToken(1), WithEmitLatestOnStart(), WithStreamStatePath(path))
+	instantWaits(s)
+	s.saveState(StreamState{Last: entries[1].Time})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := make(chan Entry)
+	go s.ResumeStream(ctx, out)
+	if got := receive(t, out, 1, cancel); len(got) != 1 || got[0] != 120 {
+		t.Errorf("got %v, want [120]", got)
+	}
+}
+
+func TestStreamDrainOnCancel(t *testing.T) {
+	for _, noDrain := range []bool{false, true} {
+		entries := readings(100, 110)
+		next := Entry{Time: entries[1].Time.Add(time.Second), Value: 120, Dir: Flat}
+		polled := make(chan bool, 10)
+		f := &fakeDexcom{}
+		f.query = func(w http.ResponseWriter, r *http.Request) {
+			// The next reading arrives while the stream waits.
+			if _, queries := f.counts(); queries == 1 {
+				serveEntries(entries)(w, r)
+			} else {
+				serveEntries(append(entries, next))(w, r)
+			}
+			polled <- true
+		}
+		opts := []Option{WithPenaltyStep(time.Hour)}
+		if noDrain {
+			opts = append(opts, WithoutDrainOnCancel())
+		}
+		s, _ := f.dial(t, testToken(1), opts...)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		out := make(chan Entry)
+		go s.StreamContext(ctx, entries[1].Time, out)
+		<-polled
+		cancel()
+		var got []int
+		for e := range out {
+			got = append(got, e.Value)
+		}
+
+		_, queries := f.counts()
+		if noDrain {
+			if len(got) != 0 || queries != 1 {
+				t.Errorf("without draining: got %v after %d queries, want nothing after 1", got, queries)
+			}
+		} else if len(got) != 1 || got[0] != 120 || queries != 2 {
+			t.Errorf("draining: got %v after %d queries, want [120] after 2", got, queries)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package dex
+
+// ComputeDir classifies the rate of change between prev and cur,
+// in mg/dL/min, into Dexcom's direction buckets. It is useful
+// for backfilling directions of entries that lack them.
+func ComputeDir(prev, cur Entry) Dir {
+	minutes := cur.Time.Sub(prev.Time).Minutes()
+	if minutes <= 0 {
+		return NotComputable
+	}
+
+	return RateDir(float64(cur.Value-prev.Value) / minutes)
+}
+
+// RateDir classifies a rate of change, in mg/dL/min, into Dexcom's
+// direction buckets.
+func RateDir(rate float64) Dir {
+	switch {
+	case rate > 3:
+		return DoubleUp
+	case rate > 2:
+		return SingleUp
+	case rate > 1:
+		return FortyFiveUp
+	case rate >= -1:
+		return Flat
+	case rate >= -2:
+		return FortyFiveDown
+	case rate >= -3:
+		return SingleDown
+	default:
+		return DoubleDown
+	}
+}
@@ -0,0 +1,39 @@
+package dex
+
+import (
+	"testing"
+	"time"
+)
+
+func TestComputeDir(t *testing.T) {
+	t0 := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		delta   int
+		minutes int
+		want    Dir
+	}{
+		{4, 1, DoubleUp},
+		{7, 2, DoubleUp},
+		{3, 1, SingleUp},
+		{5, 2, SingleUp},
+		{2, 1, FortyFiveUp},
+		{3, 2, FortyFiveUp},
+		{1, 1, Flat},
+		{0, 1, Flat},
+		{-1, 1, Flat},
+		{-3, 2, FortyFiveDown},
+		{-2, 1, FortyFiveDown},
+		{-5, 2, SingleDown},
+		{-3, 1, SingleDown},
+		{-7, 2, DoubleDown},
+		{-4, 1, DoubleDown},
+		{10, 0, NotComputable},
+		{10, -5, NotComputable},
+	} {
+		prev := Entry{Time: t0, Value: 100}
+		cur := Entry{Time: t0.Add(time.Duration(c.minutes) * time.Minute), Value: 100 + c.delta}
+		if got := ComputeDir(prev, cur); got != c.want {
+			t.Errorf("ComputeDir(%+d over %dm) = %v, want %v", c.delta, c.minutes, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package dex
+
+import "fmt"
+
+// The highest plausible glucose level in mg/dL. Dexcom reports
+// levels up to 400.
+const maxPlausible = 600
+
+// Valid returns nil if the entry is usable, or an error matching
+// ErrInvalidEntry saying why not: if it has no time, a value that
+// is not positive or is implausibly high, or a direction that is
+// unknown to this package or that Dexcom could not compute,
+// NotComputable or RateOutOfRange. Dexcom may report those with good
+// values, as while a sensor warms up; callers that can use such
+// values may check Time and Value themselves.
+func (e Entry) Valid() error {
+	switch {
+	case e.Time.IsZero():
+		return &InvalidEntryError{"no time"}
+	case e.Value <= 0:
+		return &InvalidEntryError{fmt.Sprintf("value %d", e.Value)}
+	case e.Value > maxPlausible:
+		return &InvalidEntryError{fmt.Sprintf("value %d > %d", e.Value, maxPlausible)}
+	case e.Dir < None || e.Dir > RateOutOfRange:
+		return &InvalidEntryError{fmt.Sprintf("direction %d", int(e.Dir))}
+	case e.Dir == NotComputable || e.Dir == RateOutOfRange:
+		return &InvalidEntryError{fmt.Sprintf("direction %v", e.Dir)}
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package dex
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestValid(t *testing.T) {
+	now := time.Now()
+	for _, c := range []struct {
+		name  string
+		entry Entry
+		valid bool
+	}{
+		{"ok", Entry{Time: now, Value: 100, Dir: Flat}, true},
+		{"no direction", Entry{Time: now, Value: 100}, true},
+		{"lowest", Entry{Time: now, Value: 1, Dir: Flat}, true},
+		{"highest", Entry{Time: now, Value: maxPlausible, Dir: Flat}, true},
+		{"no time", Entry{Value: 100, Dir: Flat}, false},
+		{"zero", Entry{Time: now, Value: 0, Dir: Flat}, false},
+		{"negative", Entry{Time: now, Value: -5, Dir: Flat}, false},
+		{"implausible", Entry{Time: now, Value: maxPlausible + 1, Dir: Flat}, false},
+		{"unknown direction", Entry{Time: now, Value: 100, Dir: RateOutOfRange + 1}, false},
+		{"negative direction", Entry{Time: now, Value: 100, Dir: -1}, false},
+		{"not computable", Entry{Time: now, Value: 100, Dir: NotComputable}, false},
+		{"rate out of range", Entry{Time: now, Value: 100, Dir: RateOutOfRange}, false},
+	} {
+		err := c.entry.Valid()
+		if (err == nil) != c.valid {
+			t.Errorf("%s: Valid() = %v, want valid %v", c.name, err, c.valid)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidEntry) {
+			t.Errorf("%s: %v is not ErrInvalidEntry", c.name, err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package stats
+
+import (
+	"math"
+	"time"
+
+	"basal.io/x/dex"
+)
+
+// MeanGlucose returns the mean of the entries' values in mg/dL,
+// or NaN if there are none.
+func MeanGlucose(entries []dex.Entry) float64 {
+	if len(entries) == 0 {
+		return math.NaN()
+	}
+	sum := 0
+	for i := range entries {
+		sum += entries[i].Value
+	}
+	return float64(sum) / float64(len(entries))
+}
+
+// CV returns the coefficient of variation of the entries' values,
+// the sample standard deviation as a percentage of the mean, or
+// NaN if there are fewer than two entries.
+func CV(entries []dex.Entry) float64 {
+	if len(entries) < 2 {
+		return math.NaN()
+	}
+	mean := MeanGlucose(entries)
+	ss := 0.0
+	for i := range entries {
+		d := float64(entries[i].Value) - mean
+		ss += d * d
+	}
+	sd := math.Sqrt(ss / float64(len(entries)-1))
+	return 100 * sd / mean
+}
+
+// The interval between entries beyond which AUCAbove does not
+// integrate, as the values between them are unknown.
+const maxAUCGap = 15 * time.Minute
+
+// AUCAbove returns the area under the curve of the entries' values
+// above hi, in mg/dL·min, integrating the excess of each value over
+// hi (or 0) by the trapezoidal rule between consecutive entries,
+// ordered oldest first. Gaps of more than 15 minutes are skipped.
+func AUCAbove(entries []dex.Entry, hi int) float64 {
+	auc := 0.0
+	for i := 1; i < len(entries); i++ {
+		auc += aucAbove(entries[i-1], entries[i], hi)
+	}
+	return auc
+}
+
+// The area above hi between consecutive entries e0 and e1.
+func aucAbove(e0, e1 dex.Entry, hi int) float64 {
+	d := e1.Time.Sub(e0.Time)
+	if d <= 0 || d > maxAUCGap {
+		return 0
+	}
+	x0 := math.Max(0, float64(e0.Value-hi))
+	x1 := math.Max(0, float64(e1.Value-hi))
+	return (x0 + x1) / 2 * d.Minutes()
+}
@@ -0,0 +1,54 @@
+package stats // import "basal.io/x/stats"
+
+import (
+	"math"
+	"sort"
+
+	"basal.io/x/dex"
+)
+
+// Percentile returns the p-th quantile of the entries' values,
+// for p in [0, 1], interpolating linearly between the closest
+// values. Values of p outside [0, 1] are clamped, and NaN taken
+// as 0; Percentile of no entries is 0.
+func Percentile(entries []dex.Entry, p float64) int {
+	if len(entries) == 0 {
+		return 0
+	}
+	return percentile(sorted(entries), p)
+}
+
+// Quartiles returns the first quartile, median and third quartile
+// of the entries' values.
+func Quartiles(entries []dex.Entry) (q1, median, q3 int) {
+	if len(entries) == 0 {
+		return 0, 0, 0
+	}
+	vals := sorted(entries)
+	return percentile(vals, 0.25), percentile(vals, 0.5), percentile(vals, 0.75)
+}
+
+func sorted(entries []dex.Entry) []int {
+	vals := make([]int, len(entries))
+	for i := range entries {
+		vals[i] = entries[i].Value
+	}
+	sort.Ints(vals)
+	return vals
+}
+
+func percentile(vals []int, p float64) int {
+	if !(p >= 0) {
+		p = 0
+	} else if p > 1 {
+		p = 1
+	}
+
+	pos := p * float64(len(vals)-1)
+	i := int(pos)
+	if i == len(vals)-1 {
+		return vals[i]
+	}
+	frac := pos - float64(i)
+	return int(math.Round(float64(vals[i]) + frac*float64(vals[i+1]-vals[i])))
+}
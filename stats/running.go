@@ -0,0 +1,245 @@
+package stats
+
+import (
+	"math"
+	"time"
+
+	"basal.io/x/dex"
+)
+
+// Running maintains statistics over the entries added to it within
+// a duration of the latest, updating them incrementally: adding an
+// entry takes amortized constant time, however long the window.
+// Entries must be added in order of time. Its results agree with
+// those of the corresponding functions on Entries to within
+// floating point error.
+type Running struct {
+	d       time.Duration
+	maxLen  int
+	win     []dex.Entry
+	head    int    // Index in win of the oldest entry.
+	removed uint64 // Number of entries evicted.
+
+	// Sums of values are exact; those involving times, in minutes
+	// since origin, are recomputed once as many entries have been
+	// evicted as the window holds, bounding their magnitude and
+	// accumulated error.
+	origin   time.Time
+	rebased  uint64 // The value of removed at the last rebase.
+	sy       int64
+	sx, sxx  float64
+	sxy      float64
+	min, max []seqValue // Monotonic deques, oldest first.
+
+	lo, hi, inRange int // Entries within [lo, hi], if tracked.
+	rangeSet        bool
+	aucHi           int
+	auc             float64 // The area above aucHi, if tracked.
+	aucSet          bool
+}
+
+type seqValue struct {
+	seq   uint64
+	value int
+}
+
+// A RunningOption configures a Running.
+type RunningOption func(*Running)
+
+// WithMaxLen bounds a Running's window to the latest n entries, as
+// well as by duration.
+func WithMaxLen(n int) RunningOption {
+	return func(r *Running) {
+		r.maxLen = n
+	}
+}
+
+// WithRange makes a Running count the entries in its window with
+// values in [lo, hi], as reported by InRange.
+func WithRange(lo, hi int) RunningOption {
+	return func(r *Running) {
+		r.lo, r.hi, r.rangeSet = lo, hi, true
+	}
+}
+
+// WithAUCAbove makes a Running maintain the area under the curve of
+// its window's values above hi, as reported by AUCAbove.
+func WithAUCAbove(hi int) RunningOption {
+	return func(r *Running) {
+		r.aucHi, r.aucSet = hi, true
+	}
+}
+
+// NewRunning returns a Running over the entries within d of the
+// latest added.
+func NewRunning(d time.Duration, opts ...RunningOption) *Running {
+	r := &Running{d: d}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Add e to the window, evicting entries more than d older than it,
+// and those beyond the maximum length, if any.
+func (r *Running) Add(e dex.Entry) {
+	if r.Len() == 0 {
+		r.origin = e.Time
+	}
+	seq := r.removed + uint64(r.Len())
+	if last, ok := r.Last(); ok {
+		r.auc += r.area(last, e)
+	}
+	r.win = append(r.win, e)
+	r.add(e, 1)
+
+	for len(r.min) > 0 && r.min[len(r.min)-1].value >= e.Value {
+		r.min = r.min[:len(r.min)-1]
+	}
+	r.min = append(r.min, seqValue{seq, e.Value})
+	for len(r.max) > 0 && r.max[len(r.max)-1].value <= e.Value {
+		r.max = r.max[:len(r.max)-1]
+	}
+	r.max = append(r.max, seqValue{seq, e.Value})
+
+	for r.Len() > 1 && (e.Time.Sub(r.win[r.head].Time) > r.d || r.maxLen > 0 && r.Len() > r.maxLen) {
+		r.add(r.win[r.head], -1)
+		r.auc -= r.area(r.win[r.head], r.win[r.head+1])
+		r.head++
+		r.removed++
+	}
+	for len(r.min) > 0 && r.min[0].seq < r.removed {
+		r.min = r.min[1:]
+	}
+	for len(r.max) > 0 && r.max[0].seq < r.removed {
+		r.max = r.max[1:]
+	}
+
+	if r.head > len(r.win)/2 {
+		r.win = append(r.win[:0], r.win[r.head:]...)
+		r.head = 0
+	}
+	if r.removed-r.rebased >= uint64(r.Len()) {
+		r.rebase()
+	}
+}
+
+// Add (sign 1) or remove (sign -1) e from the sums.
+func (r *Running) add(e dex.Entry, sign int) {
+	x := e.Time.Sub(r.origin).Minutes()
+	y := int64(e.Value)
+	s := float64(sign)
+	r.sy += int64(sign) * y
+	if r.rangeSet && e.Value >= r.lo && e.Value <= r.hi {
+		r.inRange += sign
+	}
+	r.sx += s * x
+	r.sxx += s * x * x
+	r.sxy += s * x * float64(y)
+}
+
+// The area between e0 and e1 above aucHi, if it is tracked.
+func (r *Running) area(e0, e1 dex.Entry) float64 {
+	if !r.aucSet {
+		return 0
+	}
+	return aucAbove(e0, e1, r.aucHi)
+}
+
+func (r *Running) rebase() {
+	r.origin = r.win[r.head].Time
+	r.rebased = r.removed
+	r.sy, r.sx, r.sxx, r.sxy = 0, 0, 0, 0
+	r.inRange, r.auc = 0, 0
+	for i, e := range r.win[r.head:] {
+		r.add(e, 1)
+		if i > 0 {
+			r.auc += r.area(r.win[r.head+i-1], e)
+		}
+	}
+}
+
+// Len returns the number of entries in the window.
+func (r *Running) Len() int {
+	return len(r.win) - r.head
+}
+
+// Entries returns the entries in the window, oldest first. The
+// slice is valid until the next call to Add.
+func (r *Running) Entries() []dex.Entry {
+	return r.win[r.head:]
+}
+
+// Last returns the latest entry, and whether there is one.
+func (r *Running) Last() (dex.Entry, bool) {
+	if r.Len() == 0 {
+		return dex.Entry{}, false
+	}
+	return r.win[len(r.win)-1], true
+}
+
+// Mean is MeanGlucose of the window.
+func (r *Running) Mean() float64 {
+	if r.Len() == 0 {
+		return math.NaN()
+	}
+	return float64(r.sy) / float64(r.Len())
+}
+
+// Slope is Slope of the window.
+func (r *Running) Slope() (float64, bool) {
+	if r.Len() < 2 {
+		return 0, false
+	}
+	n := float64(r.Len())
+	sy := float64(r.sy)
+	den := n*r.sxx - r.sx*r.sx
+	// Cancellation leaves den slightly off zero when all times are
+	// equal.
+	if math.Abs(den) <= 1e-9*n*r.sxx {
+		return 0, false
+	}
+	return (n*r.sxy - r.sx*sy) / den, true
+}
+
+// Min returns the least value in the window, and whether there is
+// one.
+func (r *Running) Min() (int, bool) {
+	if len(r.min) == 0 {
+		return 0, false
+	}
+	return r.min[0].value, true
+}
+
+// Max returns the greatest value in the window, and whether there
+// is one.
+func (r *Running) Max() (int, bool) {
+	if len(r.max) == 0 {
+		return 0, false
+	}
+	return r.max[0].value, true
+}
+
+// InRange returns the number of entries in the window with values
+// in the range given WithRange.
+func (r *Running) InRange() int {
+	return r.inRange
+}
+
+// AUCAbove is AUCAbove of the window, above the value given
+// WithAUCAbove.
+func (r *Running) AUCAbove() float64 {
+	if r.Len() < 2 {
+		return 0
+	}
+	return r.auc
+}
+
+// Reset empties the window.
+func (r *Running) Reset() {
+	*r = Running{
+		d: r.d, maxLen: r.maxLen,
+		lo: r.lo, hi: r.hi, rangeSet: r.rangeSet,
+		aucHi: r.aucHi, aucSet: r.aucSet,
+	}
+}
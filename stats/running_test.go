@@ -0,0 +1,138 @@
+package stats
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"basal.io/x/dex"
+)
+
+// A random walk of n entries, about five minutes apart, with
+// occasional gaps.
+func walk(n int) []dex.Entry {
+	rnd := rand.New(rand.NewSource(1))
+	t := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	v := 120
+	entries := make([]dex.Entry, n)
+	for i := range entries {
+		t = t.Add(5*time.Minute + time.Duration(rnd.Intn(60)-30)*time.Second)
+		if rnd.Intn(50) == 0 {
+			t = t.Add(time.Duration(rnd.Intn(60)) * time.Minute)
+		}
+		if v += rnd.Intn(21) - 10; v < 40 {
+			v = 40
+		} else if v > 400 {
+			v = 400
+		}
+		entries[i] = dex.Entry{Time: t, Value: v}
+	}
+	return entries
+}
+
+// The entries within d of the last of entries, and at most n of
+// them, if n > 0.
+func window(entries []dex.Entry, d time.Duration, n int) []dex.Entry {
+	last := entries[len(entries)-1].Time
+	i := 0
+	for last.Sub(entries[i].Time) > d || n > 0 && len(entries)-i > n {
+		i++
+	}
+	return entries[i:]
+}
+
+func minMax(entries []dex.Entry) (lo, hi int) {
+	lo, hi = entries[0].Value, entries[0].Value
+	for _, e := range entries[1:] {
+		if e.Value < lo {
+			lo = e.Value
+		}
+		if e.Value > hi {
+			hi = e.Value
+		}
+	}
+	return lo, hi
+}
+
+func TestRunningAgreesWithNaive(t *testing.T) {
+	entries := walk(2000)
+	for _, c := range []struct {
+		d time.Duration
+		n int
+	}{
+		{time.Hour, 0},
+		{24 * time.Hour, 0},
+		{24 * time.Hour, 12},
+		{time.Duration(math.MaxInt64), 5},
+	} {
+		r := NewRunning(c.d, WithMaxLen(c.n), WithRange(70, 180), WithAUCAbove(180))
+		for i, e := range entries {
+			r.Add(e)
+			win := window(entries[:i+1], c.d, c.n)
+			if r.Len() != len(win) {
+				t.Fatalf("%v/%d: entry %d: window of %d entries, want %d", c.d, c.n, i, r.Len(), len(win))
+			}
+
+			if got, want := r.Mean(), MeanGlucose(win); math.Abs(got-want) > 1e-9 {
+				t.Errorf("%v/%d: entry %d: mean %v, want %v", c.d, c.n, i, got, want)
+			}
+			got, gotOk := r.Slope()
+			want, wantOk := Slope(win)
+			if gotOk != wantOk || math.Abs(got-want) > 1e-6 {
+				t.Errorf("%v/%d: entry %d: slope %v, %v, want %v, %v", c.d, c.n, i, got, gotOk, want, wantOk)
+			}
+			lo, _ := r.Min()
+			hi, _ := r.Max()
+			if wlo, whi := minMax(win); lo != wlo || hi != whi {
+				t.Errorf("%v/%d: entry %d: range %d..%d, want %d..%d", c.d, c.n, i, lo, hi, wlo, whi)
+			}
+			in := 0
+			for _, e := range win {
+				if e.Value >= 70 && e.Value <= 180 {
+					in++
+				}
+			}
+			if r.InRange() != in {
+				t.Errorf("%v/%d: entry %d: %d in range, want %d", c.d, c.n, i, r.InRange(), in)
+			}
+			if got, want := r.AUCAbove(), AUCAbove(win, 180); math.Abs(got-want) > 1e-6*math.Max(1, want) {
+				t.Errorf("%v/%d: entry %d: AUC %v, want %v", c.d, c.n, i, got, want)
+			}
+		}
+	}
+}
+
+var sink float64
+
+// Compute statistics over a 24-hour window after each of two days'
+// entries, incrementally or from scratch.
+func BenchmarkWindow24h(b *testing.B) {
+	entries := walk(2 * 24 * 12)
+	b.Run("incremental", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			r := NewRunning(24*time.Hour, WithRange(70, 180), WithAUCAbove(180))
+			for _, e := range entries {
+				r.Add(e)
+				lo, _ := r.Min()
+				hi, _ := r.Max()
+				sink += r.Mean() + float64(hi-lo+r.InRange()) + r.AUCAbove()
+			}
+		}
+	})
+	b.Run("naive", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for j := range entries {
+				win := window(entries[:j+1], 24*time.Hour, 0)
+				lo, hi := minMax(win)
+				in := 0
+				for _, e := range win {
+					if e.Value >= 70 && e.Value <= 180 {
+						in++
+					}
+				}
+				sink += MeanGlucose(win) + float64(hi-lo+in) + AUCAbove(win, 180)
+			}
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package stats
+
+import (
+	"time"
+
+	"basal.io/x/dex"
+)
+
+// Slope returns the least-squares rate of change of the entries'
+// values, in mg/dL/min, and whether it could be computed: it needs
+// at least two entries at distinct times.
+func Slope(entries []dex.Entry) (float64, bool) {
+	if len(entries) < 2 {
+		return 0, false
+	}
+
+	t0 := entries[0].Time
+	var sx, sy, sxx, sxy float64
+	for _, e := range entries {
+		x := e.Time.Sub(t0).Minutes()
+		y := float64(e.Value)
+		sx += x
+		sy += y
+		sxx += x * x
+		sxy += x * y
+	}
+	n := float64(len(entries))
+	den := n*sxx - sx*sx
+	if den == 0 {
+		return 0, false
+	}
+	return (n*sxy - sx*sy) / den, true
+}
+
+// TimeToThreshold projects, from the slope of entries and the value
+// of the last of them, how long after it the value will reach bg.
+// It returns false if there are too few entries to fit a slope, or
+// the value is flat or moving away from bg.
+func TimeToThreshold(entries []dex.Entry, bg int) (time.Duration, bool) {
+	slope, ok := Slope(entries)
+	if !ok || slope == 0 {
+		return 0, false
+	}
+
+	minutes := float64(bg-entries[len(entries)-1].Value) / slope
+	if minutes < 0 {
+		return 0, false
+	}
+	return time.Duration(minutes * float64(time.Minute)), true
+}
+
+// LatestTrend returns the direction of the slope of the entries,
+// ordered oldest first, within window of the last, bucketed as by
+// dex.ComputeDir. It gives an arrow for sources that report none.
+// It returns None if there are too few entries to fit a slope.
+func LatestTrend(entries []dex.Entry, window time.Duration) dex.Dir {
+	if len(entries) == 0 {
+		return dex.None
+	}
+	last := entries[len(entries)-1].Time
+	i := len(entries) - 1
+	for i > 0 && last.Sub(entries[i-1].Time) <= window {
+		i--
+	}
+	slope, ok := Slope(entries[i:])
+	if !ok {
+		return dex.None
+	}
+	return dex.RateDir(slope)
+}
@@ -0,0 +1,66 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"basal.io/x/dex"
+)
+
+// Entries five minutes apart, starting at 100 and changing at rate
+// mg/dL/min.
+func ramp(start time.Time, n int, rate float64) []dex.Entry {
+	entries := make([]dex.Entry, n)
+	for i := range entries {
+		entries[i] = dex.Entry{
+			Time:  start.Add(time.Duration(i) * 5 * time.Minute),
+			Value: 100 + int(rate*float64(5*i)),
+		}
+	}
+	return entries
+}
+
+func TestLatestTrend(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		rate float64
+		want dex.Dir
+	}{
+		{4, dex.DoubleUp},
+		{2.4, dex.SingleUp},
+		{1.6, dex.FortyFiveUp},
+		{0.4, dex.Flat},
+		{0, dex.Flat},
+		{-0.8, dex.Flat},
+		{-1.6, dex.FortyFiveDown},
+		{-2.4, dex.SingleDown},
+		{-4, dex.DoubleDown},
+	} {
+		if got := LatestTrend(ramp(start, 4, c.rate), 15*time.Minute); got != c.want {
+			t.Errorf("at %.1f mg/dL/min: got %v, want %v", c.rate, got, c.want)
+		}
+	}
+
+	// Only the entries within the window count: a steep rise that
+	// has levelled off is flat.
+	entries := append(ramp(start, 4, 4), ramp(start.Add(20*time.Minute), 4, 0)...)
+	for i := 4; i < len(entries); i++ {
+		entries[i].Value = entries[3].Value
+	}
+	if got := LatestTrend(entries, 15*time.Minute); got != dex.Flat {
+		t.Errorf("after levelling off: got %v, want Flat", got)
+	}
+	if got := LatestTrend(entries, time.Hour); got != dex.FortyFiveUp {
+		t.Errorf("over the hour: got %v, want FortyFiveUp", got)
+	}
+
+	for _, entries := range [][]dex.Entry{nil, ramp(start, 1, 0)} {
+		if got := LatestTrend(entries, 15*time.Minute); got != dex.None {
+			t.Errorf("with %d entries: got %v, want None", len(entries), got)
+		}
+	}
+	// The previous entry is outside the window.
+	if got := LatestTrend(ramp(start, 2, 1), 4*time.Minute); got != dex.None {
+		t.Errorf("with one entry in the window: got %v, want None", got)
+	}
+}
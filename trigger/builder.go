@@ -0,0 +1,63 @@
+package trigger
+
+import "basal.io/x/dex"
+
+// A Builder assembles a trigger by chaining conditions, as in
+//
+//	trigger.New().Below(70).And().Arrow(dex.SingleDown).Or().Below(55).Build()
+//
+// And binds more tightly than Or, so this is
+//
+//	trigger.Any(trigger.All(trigger.Below(70), trigger.Arrow(dex.SingleDown)), trigger.Below(55))
+type Builder struct {
+	groups [][]Trigger
+}
+
+// New returns an empty Builder.
+func New() *Builder {
+	return &Builder{groups: [][]Trigger{nil}}
+}
+
+// Trigger adds t as a condition.
+func (b *Builder) Trigger(t Trigger) *Builder {
+	last := len(b.groups) - 1
+	b.groups[last] = append(b.groups[last], t)
+	return b
+}
+
+func (b *Builder) Below(bg int) *Builder         { return b.Trigger(Below(bg)) }
+func (b *Builder) Above(bg int) *Builder         { return b.Trigger(Above(bg)) }
+func (b *Builder) Arrow(dir ...dex.Dir) *Builder { return b.Trigger(Arrow(dir...)) }
+func (b *Builder) Delta(d float64) *Builder      { return b.Trigger(Delta(d)) }
+func (b *Builder) Jump(mgdl int) *Builder        { return b.Trigger(Jump(mgdl)) }
+
+// And requires the next condition as well as the previous ones.
+// Conditions are joined by And unless separated by Or.
+func (b *Builder) And() *Builder {
+	return b
+}
+
+// Or begins an alternative to the conditions before it.
+func (b *Builder) Or() *Builder {
+	b.groups = append(b.groups, nil)
+	return b
+}
+
+// Build returns the assembled trigger. Groups of a single condition
+// are not wrapped in All, nor a single group in Any.
+func (b *Builder) Build() Trigger {
+	var alts []Trigger
+	for _, g := range b.groups {
+		switch len(g) {
+		case 0:
+		case 1:
+			alts = append(alts, g[0])
+		default:
+			alts = append(alts, All(g...))
+		}
+	}
+	if len(alts) == 1 {
+		return alts[0]
+	}
+	return Any(alts...)
+}
@@ -0,0 +1,79 @@
+package trigger
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"basal.io/x/dex"
+)
+
+// The shape of t: the depth and type of each of its parts.
+func shape(t Trigger) string {
+	var parts []string
+	Walk(t, func(t Trigger, depth int) {
+		parts = append(parts, fmt.Sprintf("%d %T", depth, t))
+	})
+	return strings.Join(parts, "\n")
+}
+
+func TestBuilder(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	entries := series(start, 120, 90, 68, 60, 52, 80, 190)
+	dirs := []dex.Dir{dex.Flat, dex.SingleDown, dex.SingleDown, dex.FortyFiveDown, dex.Flat, dex.SingleUp, dex.Flat}
+	for i := range entries {
+		entries[i].Dir = dirs[i]
+	}
+
+	for _, c := range []struct {
+		name        string
+		built, hand func() Trigger
+	}{
+		{
+			"single",
+			func() Trigger { return New().Below(70).Build() },
+			func() Trigger { return Below(70) },
+		},
+		{
+			"and",
+			func() Trigger { return New().Below(70).And().Arrow(dex.SingleDown).Build() },
+			func() Trigger { return All(Below(70), Arrow(dex.SingleDown)) },
+		},
+		{
+			"or",
+			func() Trigger { return New().Below(55).Or().Above(180).Build() },
+			func() Trigger { return Any(Below(55), Above(180)) },
+		},
+		{
+			"and binds tighter than or",
+			func() Trigger {
+				return New().Below(70).And().Arrow(dex.SingleDown).Or().Below(55).Build()
+			},
+			func() Trigger { return Any(All(Below(70), Arrow(dex.SingleDown)), Below(55)) },
+		},
+		{
+			"trigger",
+			func() Trigger {
+				return New().Above(180).Or().Trigger(Distinct(Below(70))).And().Delta(-2).Build()
+			},
+			func() Trigger { return Any(Above(180), All(Distinct(Below(70)), Delta(-2))) },
+		},
+	} {
+		built, hand := c.built(), c.hand()
+		if got, want := shape(built), shape(hand); got != want {
+			t.Errorf("%s: built\n%s\nwant\n%s", c.name, got, want)
+			continue
+		}
+		for _, e := range entries {
+			built.Observe(e)
+			hand.Observe(e)
+			if got, want := Explain(built), Explain(hand); got != want {
+				t.Errorf("%s at %d: built explains %q, want %q", c.name, e.Value, got, want)
+			}
+			if got, want := built.String(), hand.String(); got != want {
+				t.Errorf("%s at %d: built is %q, want %q", c.name, e.Value, got, want)
+			}
+		}
+	}
+}
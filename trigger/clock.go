@@ -0,0 +1,150 @@
+package trigger
+
+import (
+	"fmt"
+	"time"
+
+	"basal.io/x/dex"
+)
+
+// A Clock tells the current time. Triggers that depend on wall
+// time take a Clock, so that they may be driven by a fake one;
+// a nil Clock is time.Now.
+type Clock func() time.Time
+
+func (c Clock) now() time.Time {
+	if c == nil {
+		return time.Now()
+	}
+	return c()
+}
+
+// A Resetter can be returned to its initial state.
+type Resetter interface {
+	Reset()
+}
+
+// Reset t, if it is a Resetter.
+func Reset(t Trigger) {
+	if r, ok := t.(Resetter); ok {
+		r.Reset()
+	}
+}
+
+type remindTrigger struct {
+	every  time.Duration
+	now    Clock
+	last   time.Time
+	cur    *dex.Entry
+	active bool
+}
+
+// Remind returns a trigger that is active on the first entry
+// observed once every has elapsed in wall time, as told by now (nil
+// means time.Now), since it was created, last reset, or last
+// active, whatever the entries' values. It serves for periodic
+// reminders, such as to calibrate or change a sensor. Resetting it,
+// as when the reminder is acted upon, restarts the interval.
+func Remind(every time.Duration, now Clock) Trigger {
+	return &remindTrigger{every: every, now: now, last: now.now()}
+}
+
+func (r *remindTrigger) Observe(e dex.Entry) error {
+	r.cur = &e
+	now := r.now.now()
+	r.active = now.Sub(r.last) >= r.every
+	if r.active {
+		r.last = now
+	}
+	return nil
+}
+
+func (r *remindTrigger) Reset() {
+	r.last = r.now.now()
+	r.active = false
+}
+
+func (r *remindTrigger) Current() (dex.Entry, bool) {
+	if r.cur == nil {
+		return dex.Entry{}, false
+	}
+	return *r.cur, true
+}
+
+func (r *remindTrigger) Active() bool {
+	return r.active
+}
+
+func (r *remindTrigger) TryObserve(e dex.Entry) (changed, active bool, err error) {
+	return tryObserve(r, &r.active, e)
+}
+
+func (r *remindTrigger) String() string {
+	if !r.active {
+		return ""
+	}
+	return fmt.Sprintf("Remind(%v)", r.every)
+}
+
+type freshTrigger struct {
+	t      Trigger
+	maxAge time.Duration
+	now    Clock
+	cur    *dex.Entry
+}
+
+// Fresh returns a trigger that behaves as t while the latest entry
+// observed is no older than maxAge in wall time, as told by now (nil
+// means time.Now), and is inactive otherwise. It keeps a stalled
+// stream from holding an alarm on, or acting upon, an old reading.
+// Explain tells a stale trigger from one that is merely inactive.
+func Fresh(maxAge time.Duration, now Clock, t Trigger) Trigger {
+	return &freshTrigger{t: t, maxAge: maxAge, now: now}
+}
+
+func (f *freshTrigger) Observe(e dex.Entry) error {
+	f.cur = &e
+	return f.t.Observe(e)
+}
+
+// Age returns the age of the latest entry, and whether there is one.
+func (f *freshTrigger) age() (time.Duration, bool) {
+	if f.cur == nil {
+		return 0, false
+	}
+	return f.now.now().Sub(f.cur.Time), true
+}
+
+func (f *freshTrigger) stale() bool {
+	age, ok := f.age()
+	return !ok || age > f.maxAge
+}
+
+func (f *freshTrigger) Current() (dex.Entry, bool) {
+	if f.cur == nil {
+		return dex.Entry{}, false
+	}
+	return *f.cur, true
+}
+
+func (f *freshTrigger) Active() bool {
+	return !f.stale() && f.t.Active()
+}
+
+func (f *freshTrigger) String() string {
+	if f.stale() {
+		return ""
+	}
+	return f.t.String()
+}
+
+func (f *freshTrigger) Explain() string {
+	if age, ok := f.age(); !ok {
+		return "stale: no readings"
+	} else if age > f.maxAge {
+		return fmt.Sprintf("stale: reading is %v old", age.Round(time.Second))
+	}
+	return Explain(f.t)
+}
+
+func (f *freshTrigger) Children() []Trigger { return []Trigger{f.t} }
@@ -0,0 +1,126 @@
+package trigger
+
+import (
+	"testing"
+	"time"
+
+	"basal.io/x/dex"
+)
+
+// A fakeClock is a Clock for tests, whose time moves only when
+// advanced.
+type fakeClock struct {
+	t time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{t: time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+// Clock returns the Clock telling c's time.
+func (c *fakeClock) Clock() Clock {
+	return func() time.Time { return c.t }
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+// Observe value with t at the clock's current time.
+func (c *fakeClock) observe(t Trigger, value int) {
+	t.Observe(dex.Entry{Time: c.t, Value: value})
+}
+
+func TestNilClock(t *testing.T) {
+	var c Clock
+	if d := time.Since(c.now()); d < 0 || d > time.Minute {
+		t.Errorf("nil Clock is %v from now", d)
+	}
+}
+
+func TestRateLimitActivations(t *testing.T) {
+	clock := newFakeClock()
+	r := RateLimitActivations(10*time.Minute, clock.Clock(), Below(70))
+
+	for _, c := range []struct {
+		advance time.Duration
+		value   int
+		want    bool
+	}{
+		{0, 60, true},
+		{time.Minute, 60, false},
+		{5 * time.Minute, 60, false},
+		{4 * time.Minute, 60, true},
+		{10 * time.Minute, 100, false},
+		{time.Minute, 60, true},
+	} {
+		clock.advance(c.advance)
+		clock.observe(r, c.value)
+		if got := r.Active(); got != c.want {
+			t.Errorf("at %v, value %d: Active() = %v, want %v",
+				clock.t.Format("15:04"), c.value, got, c.want)
+		}
+	}
+}
+
+func TestRemind(t *testing.T) {
+	clock := newFakeClock()
+	r := Remind(time.Hour, clock.Clock())
+
+	for _, c := range []struct {
+		advance time.Duration
+		reset   bool
+		want    bool
+	}{
+		{5 * time.Minute, false, false},
+		{55 * time.Minute, false, true},
+		{5 * time.Minute, false, false},
+		{50 * time.Minute, false, false},
+		{5 * time.Minute, false, true},
+		// Acting on the reminder restarts the interval.
+		{30 * time.Minute, true, false},
+		{59 * time.Minute, false, false},
+		{time.Minute, false, true},
+	} {
+		clock.advance(c.advance)
+		if c.reset {
+			Reset(r)
+		}
+		clock.observe(r, 100)
+		if got := r.Active(); got != c.want {
+			t.Errorf("at %v: Active() = %v, want %v", clock.t.Format("15:04"), got, c.want)
+		}
+	}
+}
+
+func TestFresh(t *testing.T) {
+	clock := newFakeClock()
+	f := Fresh(15*time.Minute, clock.Clock(), Below(70))
+
+	if f.Active() || Explain(f) != "stale: no readings" {
+		t.Errorf("with no readings: %s", Explain(f))
+	}
+	clock.observe(f, 60)
+	if !f.Active() || f.String() == "" {
+		t.Errorf("with a fresh low: %s", Explain(f))
+	}
+
+	// The stream stalls: no entries arrive, but time passes.
+	clock.advance(15 * time.Minute)
+	if !f.Active() {
+		t.Errorf("at the limit of freshness: %s", Explain(f))
+	}
+	clock.advance(time.Second)
+	if f.Active() || f.String() != "" {
+		t.Errorf("with a stale low: %s", Explain(f))
+	}
+	if got, want := Explain(f), "stale: reading is 15m1s old"; got != want {
+		t.Errorf("Explain() = %q, want %q", got, want)
+	}
+
+	// The stream resumes.
+	clock.observe(f, 65)
+	if !f.Active() {
+		t.Errorf("with the stream resumed: %s", Explain(f))
+	}
+}
@@ -0,0 +1,83 @@
+package trigger
+
+import (
+	"sync"
+
+	"basal.io/x/dex"
+)
+
+var counters struct {
+	sync.Mutex
+	m map[string]uint64
+}
+
+// Counters returns a snapshot of the activation counts recorded by
+// Counted triggers, by name.
+func Counters() map[string]uint64 {
+	counters.Lock()
+	defer counters.Unlock()
+	m := make(map[string]uint64, len(counters.m))
+	for name, n := range counters.m {
+		m[name] = n
+	}
+	return m
+}
+
+// ResetCounters zeroes all activation counts.
+func ResetCounters() {
+	counters.Lock()
+	defer counters.Unlock()
+	counters.m = nil
+}
+
+func count(name string) {
+	counters.Lock()
+	defer counters.Unlock()
+	if counters.m == nil {
+		counters.m = make(map[string]uint64)
+	}
+	counters.m[name]++
+}
+
+type countedTrigger struct {
+	name   string
+	t      Trigger
+	active bool
+}
+
+// Counted returns a trigger that behaves as t, counting under name,
+// as reported by Counters, each time t becomes active. Triggers may
+// share a name, and so a count. Counts are safe to update and read
+// concurrently, though each trigger must be observed by one
+// goroutine at a time.
+func Counted(name string, t Trigger) Trigger {
+	return &countedTrigger{name: name, t: t}
+}
+
+func (c *countedTrigger) Observe(e dex.Entry) error {
+	err := c.t.Observe(e)
+	active := c.t.Active()
+	if active && !c.active {
+		count(c.name)
+	}
+	c.active = active
+	return err
+}
+
+func (c *countedTrigger) Current() (dex.Entry, bool) {
+	return Current(c.t)
+}
+
+func (c *countedTrigger) Active() bool {
+	return c.t.Active()
+}
+
+func (c *countedTrigger) TryObserve(e dex.Entry) (changed, active bool, err error) {
+	return tryObserve(c, &c.active, e)
+}
+
+func (c *countedTrigger) String() string {
+	return c.t.String()
+}
+
+func (c *countedTrigger) Children() []Trigger { return []Trigger{c.t} }
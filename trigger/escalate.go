@@ -0,0 +1,104 @@
+package trigger
+
+import (
+	"fmt"
+
+	"basal.io/x/dex"
+)
+
+// An EscalationLevel is a tier of an escalating alarm.
+type EscalationLevel struct {
+	Severity string  // A label for the tier, e.g. "page".
+	Trigger  Trigger // The trigger for the tier.
+}
+
+type escalateTrigger []EscalationLevel
+
+// A Severer reports the severity of its current state.
+type Severer interface {
+	Severity() string
+}
+
+// Severity returns the severity of t, if it is a Severer, or "".
+func Severity(t Trigger) string {
+	if s, ok := t.(Severer); ok {
+		return s.Severity()
+	}
+	return ""
+}
+
+// Escalate returns a trigger that is active when any of levels is,
+// given in increasing order of severity. Its message is that of the
+// most severe active level, labelled with its severity. For example
+//
+//	Escalate(
+//		EscalationLevel{"notify", Below(80)},
+//		EscalationLevel{"alert", Below(70)},
+//		EscalationLevel{"page", Below(55)})
+func Escalate(levels ...EscalationLevel) Trigger {
+	return escalateTrigger(levels)
+}
+
+func (x escalateTrigger) Observe(e dex.Entry) error {
+	var errs errs
+	for _, l := range x {
+		errs.record(l.Trigger.Observe(e))
+	}
+
+	return errs.err()
+}
+
+func (x escalateTrigger) ObserveBatch(entries []dex.Entry) error {
+	var errs errs
+	for _, l := range x {
+		errs.record(ObserveAll(l.Trigger, entries))
+	}
+
+	return errs.err()
+}
+
+// Top returns the most severe active level.
+func (x escalateTrigger) top() (EscalationLevel, bool) {
+	for i := len(x) - 1; i >= 0; i-- {
+		if x[i].Trigger.Active() {
+			return x[i], true
+		}
+	}
+	return EscalationLevel{}, false
+}
+
+func (x escalateTrigger) Active() bool {
+	_, ok := x.top()
+	return ok
+}
+
+// Severity returns the label of the most severe active level, or ""
+// if none is active.
+func (x escalateTrigger) Severity() string {
+	l, _ := x.top()
+	return l.Severity
+}
+
+func (x escalateTrigger) String() string {
+	l, ok := x.top()
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%s: %s", l.Severity, l.Trigger.String())
+}
+
+func (x escalateTrigger) Explain() string {
+	return explain("Escalate", x.Active(), x.Children())
+}
+
+func (x escalateTrigger) Current() (dex.Entry, bool) {
+	return latest(x.Children())
+}
+
+func (x escalateTrigger) Children() []Trigger {
+	ts := make([]Trigger, len(x))
+	for i := range x {
+		ts[i] = x[i].Trigger
+	}
+	return ts
+}
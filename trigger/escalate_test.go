@@ -0,0 +1,43 @@
+package trigger
+
+import (
+	"testing"
+	"time"
+
+	"basal.io/x/dex"
+)
+
+func TestEscalate(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	x := Escalate(
+		EscalationLevel{"notify", Below(80)},
+		EscalationLevel{"alert", Below(70)},
+		EscalationLevel{"page", Any(Below(55), Arrow(dex.DoubleDown))},
+	)
+	entries := series(start, 100, 78, 68, 52, 75, 120, 90)
+	// A fast fall pages though the value is in range.
+	entries[6].Dir = dex.DoubleDown
+	for i, want := range []struct {
+		severity, msg string
+	}{
+		{"", ""},
+		{"notify", "notify: 78 < 80"},
+		{"alert", "alert: 68 < 70"},
+		{"page", "page: Any(52 < 55)"},
+		{"notify", "notify: 75 < 80"},
+		{"", ""},
+		{"page", "page: Any(⇊)"},
+	} {
+		e := entries[i]
+		x.Observe(e)
+		if got := Severity(x); got != want.severity {
+			t.Errorf("at %d: severity %q, want %q", e.Value, got, want.severity)
+		}
+		if got := x.String(); got != want.msg {
+			t.Errorf("at %d: String() = %q, want %q", e.Value, got, want.msg)
+		}
+		if got := x.Active(); got != (want.severity != "") {
+			t.Errorf("at %d: Active() = %v", e.Value, got)
+		}
+	}
+}
@@ -2,8 +2,11 @@ package trigger
 
 import (
 	"fmt"
+	"math"
+	"time"
 
 	"basal.io/x/dex"
+	"basal.io/x/stats"
 )
 
 func Below(bg int) Trigger {
@@ -26,6 +29,37 @@ func Above(bg int) Trigger {
 	})
 }
 
+// BelowMmol is Below with the level bg given in mmol/L, and its
+// message in mmol/L.
+func BelowMmol(bg float64) Trigger {
+	mgdl := mmolToMgdl(bg)
+	return Predicate(func(e dex.Entry) string {
+		if e.Value < mgdl {
+			return fmt.Sprintf("%.1f < %.1f mmol/L", e.Mmol(), bg)
+		} else {
+			return ""
+		}
+	})
+}
+
+// AboveMmol is Above with the level bg given in mmol/L, and its
+// message in mmol/L.
+func AboveMmol(bg float64) Trigger {
+	mgdl := mmolToMgdl(bg)
+	return Predicate(func(e dex.Entry) string {
+		if e.Value > mgdl {
+			return fmt.Sprintf("%.1f > %.1f mmol/L", e.Mmol(), bg)
+		} else {
+			return ""
+		}
+	})
+}
+
+// The level in mg/dL nearest bg in mmol/L.
+func mmolToMgdl(bg float64) int {
+	return int(math.Round(bg * dex.MgdlPerMmol))
+}
+
 func Arrow(dir ...dex.Dir) Trigger {
 	return Predicate(func(e dex.Entry) string {
 		for _, d := range dir {
@@ -37,6 +71,33 @@ func Arrow(dir ...dex.Dir) Trigger {
 	})
 }
 
+// ValueAndDir fires when pred, given the value and direction of
+// the same entry, returns a message.
+func ValueAndDir(pred func(value int, dir dex.Dir) string) Trigger {
+	return Predicate(func(e dex.Entry) string {
+		return pred(e.Value, e.Dir)
+	})
+}
+
+// LowAndFalling fires when an entry is below bg and trending in one
+// of dirs, by default SingleDown or DoubleDown.
+func LowAndFalling(bg int, dirs ...dex.Dir) Trigger {
+	if len(dirs) == 0 {
+		dirs = []dex.Dir{dex.SingleDown, dex.DoubleDown}
+	}
+	return ValueAndDir(func(value int, dir dex.Dir) string {
+		if value >= bg {
+			return ""
+		}
+		for _, d := range dirs {
+			if d == dir {
+				return fmt.Sprintf("%d < %d %s", value, bg, dir.Arrow())
+			}
+		}
+		return ""
+	})
+}
+
 // Delta in mg/dL/m
 func Delta(d float64) Trigger {
 	return Predicate2(func(e0, e1 dex.Entry) string {
@@ -49,4 +110,156 @@ func Delta(d float64) Trigger {
 			return ""
 		}
 	})
-}
\ No newline at end of file
+}
+
+// Urgency fires when a score combining how low the value is and
+// how fast it is falling exceeds threshold. The score is
+//
+//	valueWeight*max(0, (100-value)/30) + rateWeight*max(0, -rate/2)
+//
+// where value is the latest value in mg/dL and rate the change
+// since the previous entry in mg/dL/min: each term is 1 at 70 mg/dL
+// or a fall of 2 mg/dL/min.
+func Urgency(threshold float64, valueWeight, rateWeight float64) Trigger {
+	return Predicate2(func(e0, e1 dex.Entry) string {
+		minutes := e1.Time.Sub(e0.Time).Minutes()
+		if minutes <= 0 {
+			return ""
+		}
+		rate := float64(e1.Value-e0.Value) / minutes
+		score := valueWeight*math.Max(0, float64(100-e1.Value)/30) +
+			rateWeight*math.Max(0, -rate/2)
+		if score > threshold {
+			return fmt.Sprintf("Urgency(%.2f > %.2f)", score, threshold)
+		} else {
+			return ""
+		}
+	})
+}
+
+// Inconsistent fires when the direction Dexcom reports for an entry
+// disagrees with its change from the previous entry: when the rate
+// of change lies more than tolerance mg/dL/min outside the range of
+// rates of the reported direction (as classified by dex.ComputeDir).
+// Entries without a reported direction are never inconsistent.
+func Inconsistent(tolerance float64) Trigger {
+	return Predicate2(func(e0, e1 dex.Entry) string {
+		minutes := e1.Time.Sub(e0.Time).Minutes()
+		lo, hi, ok := dirRates(e1.Dir)
+		if minutes <= 0 || !ok {
+			return ""
+		}
+		rate := float64(e1.Value-e0.Value) / minutes
+		if rate < lo-tolerance || rate > hi+tolerance {
+			return fmt.Sprintf("Inconsistent(%s, computed %s)",
+				e1.Dir.Arrow(), dex.ComputeDir(e0, e1).Arrow())
+		} else {
+			return ""
+		}
+	})
+}
+
+// The range of rates, in mg/dL/min, of direction d.
+func dirRates(d dex.Dir) (lo, hi float64, ok bool) {
+	inf := math.Inf(1)
+	switch d {
+	case dex.DoubleUp:
+		return 3, inf, true
+	case dex.SingleUp:
+		return 2, 3, true
+	case dex.FortyFiveUp:
+		return 1, 2, true
+	case dex.Flat:
+		return -1, 1, true
+	case dex.FortyFiveDown:
+		return -2, -1, true
+	case dex.SingleDown:
+		return -3, -2, true
+	case dex.DoubleDown:
+		return -inf, -3, true
+	default:
+		return 0, 0, false
+	}
+}
+
+// Jump fires when the value changes by more than mgdl between
+// consecutive entries, however far apart. Unlike Delta, it does
+// not normalize the change by time.
+func Jump(mgdl int) Trigger {
+	return Predicate2(func(e0, e1 dex.Entry) string {
+		jump := e1.Value - e0.Value
+		if jump > mgdl || -jump > mgdl {
+			return fmt.Sprintf("Jump(%d -> %d: %+d)", e0.Value, e1.Value, jump)
+		} else {
+			return ""
+		}
+	})
+}
+
+// SessionStart fires on the first entry after a gap longer than
+// maxGap, as when a new sensor finishes warming up.
+func SessionStart(maxGap time.Duration) Trigger {
+	return Predicate2(func(e0, e1 dex.Entry) string {
+		gap := e1.Time.Sub(e0.Time)
+		if gap > maxGap {
+			return fmt.Sprintf("SessionStart(after %v)", gap)
+		} else {
+			return ""
+		}
+	})
+}
+
+// Swing fires when the spread between the highest and lowest of
+// the last n values exceeds mgdl.
+func Swing(n int, mgdl int) Trigger {
+	return running(lastN(n), func(r *stats.Running) string {
+		if r.Len() < n {
+			return ""
+		}
+		lo, _ := r.Min()
+		hi, _ := r.Max()
+		if hi-lo > mgdl {
+			return fmt.Sprintf("Swing(%d..%d: %d > %d)", lo, hi, hi-lo, mgdl)
+		} else {
+			return ""
+		}
+	})
+}
+
+// Flatline fires when the last n values are identical, as when a
+// sensor is stuck.
+func Flatline(n int) Trigger {
+	return running(lastN(n), func(r *stats.Running) string {
+		lo, _ := r.Min()
+		hi, _ := r.Max()
+		if r.Len() < n || lo != hi {
+			return ""
+		}
+		return fmt.Sprintf("Flatline(%d x %d)", lo, n)
+	})
+}
+
+// A window of the last n entries, however old.
+func lastN(n int) *stats.Running {
+	return stats.NewRunning(time.Duration(math.MaxInt64), stats.WithMaxLen(n))
+}
+
+// The number of entries over which SamplingDegraded averages the
+// interval between readings: an hour's worth, normally.
+const samplingWindow = 13
+
+// SamplingDegraded fires when the average interval between the last
+// several readings exceeds expected*factor, as when a connection
+// degrades, before the feed stalls altogether. Wrap it in Measured
+// if synthetic entries are interpolated.
+func SamplingDegraded(expected time.Duration, factor float64) Trigger {
+	limit := time.Duration(float64(expected) * factor)
+	return Window(samplingWindow, func(es []dex.Entry) string {
+		avg := es[len(es)-1].Time.Sub(es[0].Time) / time.Duration(len(es)-1)
+		if avg > limit {
+			return fmt.Sprintf("SamplingDegraded(every %v > %v)", avg.Round(time.Second), limit)
+		} else {
+			return ""
+		}
+	})
+}
@@ -0,0 +1,26 @@
+package trigger
+
+import (
+	"testing"
+	"time"
+
+	"basal.io/x/dex"
+)
+
+func TestMmolLevels(t *testing.T) {
+	for _, c := range []struct {
+		t     Trigger
+		value int
+		want  string
+	}{
+		{BelowMmol(3.9), 70, ""},
+		{BelowMmol(3.9), 69, "3.8 < 3.9 mmol/L"},
+		{AboveMmol(10), 180, ""},
+		{AboveMmol(10), 182, "10.1 > 10.0 mmol/L"},
+	} {
+		c.t.Observe(dex.Entry{Time: time.Now(), Value: c.value})
+		if got := c.t.String(); got != c.want {
+			t.Errorf("%d mg/dL: got %q, want %q", c.value, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package trigger
+
+import (
+	"context"
+	"time"
+
+	"basal.io/x/dex"
+)
+
+// Monitor streams entries from src, starting now, into trig. It
+// calls onFire with the entry and trig's message each time trig
+// goes from inactive to active. Each entry is observed, and trig
+// evaluated, in turn, even when entries arrive together, as when
+// the stream catches up, so that no firing is missed. Monitor
+// returns when ctx is done, the stream ends, or trig fails to
+// observe an entry.
+func Monitor(ctx context.Context, src dex.Source, trig Trigger, onFire func(dex.Entry, string)) error {
+	return monitor(ctx, src, time.Now(), time.Time{}, trig, onFire)
+}
+
+// Backtest is like Monitor, but streams all of src's entries, as
+// from a dex.BacktestSource, to see when trig would have fired.
+// Entries before from warm up trig, as a single batch, without
+// being reported; those after are observed one at a time, as by
+// Monitor. Backtest returns nil once the stream ends.
+func Backtest(ctx context.Context, src dex.Source, trig Trigger, from time.Time, onFire func(dex.Entry, string)) error {
+	return monitor(ctx, src, time.Time{}, from, trig, onFire)
+}
+
+func monitor(ctx context.Context, src dex.Source, begin, from time.Time, trig Trigger, onFire func(dex.Entry, string)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	entries := make(chan dex.Entry)
+	go src.StreamContext(ctx, begin, entries)
+	// Let the stream wind down, draining what it may, without us.
+	defer func() {
+		go func() {
+			for range entries {
+			}
+		}()
+	}()
+
+	var warmup []dex.Entry
+	for {
+		select {
+		case e, ok := <-entries:
+			if !ok {
+				if err := ObserveAll(trig, warmup); err != nil {
+					return err
+				}
+				return ctx.Err()
+			}
+			if e.Time.Before(from) {
+				warmup = append(warmup, e)
+				continue
+			}
+			if len(warmup) > 0 {
+				if err := ObserveAll(trig, warmup); err != nil {
+					return err
+				}
+				warmup = nil
+			}
+
+			changed, active, err := TryObserve(trig, e)
+			if err != nil {
+				return err
+			}
+			if changed && active {
+				onFire(e, trig.String())
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package trigger
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"basal.io/x/dex"
+)
+
+// A sliceSource streams its entries, oldest first, then ends.
+type sliceSource []dex.Entry
+
+func (s sliceSource) StreamContext(ctx context.Context, begin time.Time, out chan<- dex.Entry) {
+	defer close(out)
+	for _, e := range s {
+		if !e.Time.After(begin) {
+			continue
+		}
+		select {
+		case out <- e:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Entries of values, five minutes apart, starting at start.
+func series(start time.Time, values ...int) sliceSource {
+	entries := make(sliceSource, len(values))
+	for i, v := range values {
+		entries[i] = dex.Entry{Time: start.Add(time.Duration(i) * 5 * time.Minute), Value: v}
+	}
+	return entries
+}
+
+func TestBacktestWarmup(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	src := series(start, 60, 60, 100, 60)
+	for _, c := range []struct {
+		from  time.Time
+		fired []int
+	}{
+		{time.Time{}, []int{0, 3}},
+		{start.Add(10 * time.Minute), []int{3}},
+		{start.Add(time.Hour), nil},
+	} {
+		var fired []int
+		err := Backtest(context.Background(), src, Below(70), c.from, func(e dex.Entry, msg string) {
+			fired = append(fired, int(e.Time.Sub(start)/(5*time.Minute)))
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(fired) != fmt.Sprint(c.fired) {
+			t.Errorf("from %v: fired at %v, want %v", c.from, fired, c.fired)
+		}
+	}
+}
+
+func TestBacktestWarmsUpWindow(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	src := series(start, 50, 50, 50, 100)
+	low := Window(3, func(entries []dex.Entry) string {
+		for _, e := range entries {
+			if e.Value >= 70 {
+				return ""
+			}
+		}
+		return "low"
+	})
+	// The window fills, and goes active, during warm-up, which is
+	// not reported.
+	var fired int
+	err := Backtest(context.Background(), src, low, start.Add(15*time.Minute), func(dex.Entry, string) { fired++ })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fired != 0 {
+		t.Errorf("fired %d times, want 0", fired)
+	}
+}
+
+func TestMonitorFiresOncePerActivation(t *testing.T) {
+	// The source delivers its entries at once, as when a stream
+	// catches up; each is observed in turn.
+	for _, c := range []struct {
+		values []int
+		want   []string
+	}{
+		{[]int{100, 60, 100}, []string{"60 < 70"}},
+		{[]int{60, 60, 60, 100, 65, 60}, []string{"60 < 70", "65 < 70"}},
+		{[]int{100, 60, 100, 62, 100, 64}, []string{"60 < 70", "62 < 70", "64 < 70"}},
+		{[]int{100, 100}, nil},
+	} {
+		src := series(time.Now().Add(time.Minute), c.values...)
+		var fired []string
+		onFire := func(e dex.Entry, msg string) {
+			fired = append(fired, msg)
+		}
+		if err := Monitor(context.Background(), src, Below(70), onFire); err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(fired) != fmt.Sprint(c.want) {
+			t.Errorf("%v: fired %q, want %q", c.values, fired, c.want)
+		}
+	}
+}
+
+// The mean of a day of entries, in a trigger that is evaluated
+// as each entry is observed.
+func dailyMean() Trigger {
+	return Span(24*time.Hour, func(entries []dex.Entry) string {
+		sum := 0
+		for _, e := range entries {
+			sum += e.Value
+		}
+		if len(entries) > 0 && sum/len(entries) > 180 {
+			return "high"
+		}
+		return ""
+	})
+}
+
+// Backtest three days of entries, warming up on all but the last
+// if warmup is set, and observing each otherwise.
+func benchmarkBacktest(b *testing.B, warmup bool) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	values := make([]int, 3*24*12)
+	for i := range values {
+		values[i] = 100 + i%150
+	}
+	src := series(start, values...)
+	from := time.Time{}
+	if warmup {
+		from = src[len(src)-1].Time
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := Backtest(context.Background(), src, dailyMean(), from, func(dex.Entry, string) {}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBacktestWarmup(b *testing.B)   { benchmarkBacktest(b, true) }
+func BenchmarkBacktestPerEntry(b *testing.B) { benchmarkBacktest(b, false) }
@@ -0,0 +1,158 @@
+package trigger
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"basal.io/x/dex"
+)
+
+type reboundTrigger struct {
+	lowBG  int
+	within time.Duration
+	rise   int
+
+	low *dex.Entry // The nadir of the current low, if any.
+	cur dex.Entry
+}
+
+// Rebound fires when, within the given duration of a reading below
+// lowBG, the value has risen at least rise mg/dL above the lowest
+// such reading.
+func Rebound(lowBG int, within time.Duration, rise int) Trigger {
+	return &reboundTrigger{lowBG: lowBG, within: within, rise: rise}
+}
+
+func (r *reboundTrigger) Observe(e dex.Entry) error {
+	r.cur = e
+	if r.low != nil && e.Time.Sub(r.low.Time) > r.within {
+		r.low = nil
+	}
+	if e.Value < r.lowBG && (r.low == nil || e.Value <= r.low.Value) {
+		low := e
+		r.low = &low
+	}
+	return nil
+}
+
+func (r *reboundTrigger) Active() bool {
+	return r.low != nil && r.cur.Value-r.low.Value >= r.rise
+}
+
+func (r *reboundTrigger) String() string {
+	if !r.Active() {
+		return ""
+	}
+	return fmt.Sprintf("Rebound(%d at %s, then %d after %v)",
+		r.low.Value, r.low.Time.Format("15:04"), r.cur.Value, r.cur.Time.Sub(r.low.Time))
+}
+
+func (r *reboundTrigger) Current() (dex.Entry, bool) {
+	return r.cur, !r.cur.Time.IsZero()
+}
+
+type coincideTrigger struct {
+	window       time.Duration
+	a, b         Trigger
+	lastA, lastB time.Time // When a and b were last active.
+	cur          time.Time
+}
+
+// Coincide fires when both a and b have been active on some entry
+// within window of the latest, though not necessarily the same one.
+func Coincide(window time.Duration, a, b Trigger) Trigger {
+	return &coincideTrigger{window: window, a: a, b: b}
+}
+
+func (c *coincideTrigger) Observe(e dex.Entry) error {
+	var errs errs
+	errs.record(c.a.Observe(e))
+	errs.record(c.b.Observe(e))
+
+	c.cur = e.Time
+	if c.a.Active() {
+		c.lastA = e.Time
+	}
+	if c.b.Active() {
+		c.lastB = e.Time
+	}
+	return errs.err()
+}
+
+func (c *coincideTrigger) Active() bool {
+	return !c.lastA.IsZero() && !c.lastB.IsZero() &&
+		c.cur.Sub(c.lastA) <= c.window && c.cur.Sub(c.lastB) <= c.window
+}
+
+func (c *coincideTrigger) String() string {
+	if !c.Active() {
+		return ""
+	}
+	return fmt.Sprintf("Coincide(%s, %s)",
+		c.lastA.Format("15:04"), c.lastB.Format("15:04"))
+}
+
+func (c *coincideTrigger) Current() (dex.Entry, bool) {
+	return latest([]Trigger{c.a, c.b})
+}
+
+func (c *coincideTrigger) Children() []Trigger { return []Trigger{c.a, c.b} }
+
+// DayDelta fires when the value differs by more than mgdl from that
+// of the baseline at the same time of day, as "this time yesterday":
+// the latest entry of baseline from a previous day whose time of day
+// is within tolerance of the current one's. Entries of baseline
+// within a day, less tolerance, of the current one are ignored.
+func DayDelta(baseline []dex.Entry, mgdl int, tolerance time.Duration) Trigger {
+	base := make([]dex.Entry, len(baseline))
+	copy(base, baseline)
+	sort.SliceStable(base, func(i, j int) bool {
+		return base[i].Time.Before(base[j].Time)
+	})
+
+	return Predicate(func(e dex.Entry) string {
+		b, ok := sameTimeOfDay(base, e.Time, tolerance)
+		if !ok {
+			return ""
+		}
+		delta := e.Value - b.Value
+		if delta > mgdl || -delta > mgdl {
+			return fmt.Sprintf("DayDelta(%d vs %d at %s: %+d)",
+				e.Value, b.Value, b.Time.Format("Jan 2 15:04"), delta)
+		} else {
+			return ""
+		}
+	})
+}
+
+// SameTimeOfDay returns the latest of entries, ordered oldest first,
+// from a day or more, less tolerance, before t whose time of day is
+// within tolerance of t's.
+func sameTimeOfDay(entries []dex.Entry, t time.Time, tolerance time.Duration) (dex.Entry, bool) {
+	const day = 24 * time.Hour
+	tod := sinceMidnight(t)
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if t.Sub(e.Time) < day-tolerance {
+			continue
+		}
+		d := sinceMidnight(e.Time.In(t.Location())) - tod
+		if d < 0 {
+			d = -d
+		}
+		if d > day/2 {
+			d = day - d
+		}
+		if d <= tolerance {
+			return e, true
+		}
+	}
+	return dex.Entry{}, false
+}
+
+func sinceMidnight(t time.Time) time.Duration {
+	h, m, s := t.Clock()
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute +
+		time.Duration(s)*time.Second + time.Duration(t.Nanosecond())
+}
@@ -0,0 +1,41 @@
+package trigger
+
+import (
+	"testing"
+	"time"
+
+	"basal.io/x/dex"
+)
+
+func TestDayDelta(t *testing.T) {
+	now := time.Date(2020, 1, 2, 8, 0, 0, 0, time.UTC)
+	baseline := []dex.Entry{
+		{Time: now.Add(-48 * time.Hour), Value: 90},
+		{Time: now.Add(-24*time.Hour - 4*time.Minute), Value: 100},
+		{Time: now.Add(-24*time.Hour + 20*time.Minute), Value: 300},
+		// Today's history is not "this time yesterday".
+		{Time: now.Add(-10 * time.Minute), Value: 200},
+		{Time: now.Add(-5 * time.Minute), Value: 200},
+	}
+	for _, c := range []struct {
+		value int
+		want  string
+	}{
+		{120, ""},
+		{130, "DayDelta(130 vs 100 at Jan 1 07:56: +30)"},
+		{50, "DayDelta(50 vs 100 at Jan 1 07:56: -50)"},
+	} {
+		d := DayDelta(baseline, 25, 10*time.Minute)
+		d.Observe(dex.Entry{Time: now, Value: c.value})
+		if got := d.String(); got != c.want {
+			t.Errorf("at %d: got %q, want %q", c.value, got, c.want)
+		}
+	}
+
+	// With only today's history, there is nothing to compare to.
+	d := DayDelta(baseline[3:], 25, 10*time.Minute)
+	d.Observe(dex.Entry{Time: now, Value: 100})
+	if d.Active() {
+		t.Errorf("compared to today's history: %s", d)
+	}
+}
@@ -1,6 +1,11 @@
 package trigger
 
-import "basal.io/x/dex"
+import (
+	"time"
+
+	"basal.io/x/dex"
+	"basal.io/x/stats"
+)
 
 type predicateTrigger struct {
 	p   func(dex.Entry) string
@@ -21,11 +26,28 @@ func (p *predicateTrigger) Observe(e dex.Entry) error {
 	return nil
 }
 
+func (p *predicateTrigger) ObserveBatch(entries []dex.Entry) error {
+	if len(entries) > 0 {
+		return p.Observe(entries[len(entries)-1])
+	}
+	return nil
+}
+
+func (p *predicateTrigger) Current() (dex.Entry, bool) {
+	if p.cur == nil {
+		return dex.Entry{}, false
+	}
+	return *p.cur, true
+}
+
 func (p *predicateTrigger) Active() bool {
-	return p.p(*p.cur) != ""
+	return p.String() != ""
 }
 
 func (p *predicateTrigger) String() string {
+	if p.cur == nil {
+		return ""
+	}
 	return p.p(*p.cur)
 }
 
@@ -39,6 +61,23 @@ func (p *predicate2Trigger) Observe(e dex.Entry) error {
 	return nil
 }
 
+func (p *predicate2Trigger) ObserveBatch(entries []dex.Entry) error {
+	if len(entries) > 2 {
+		entries = entries[len(entries)-2:]
+	}
+	for _, e := range entries {
+		p.Observe(e)
+	}
+	return nil
+}
+
+func (p *predicate2Trigger) Current() (dex.Entry, bool) {
+	if p.cur == nil {
+		return dex.Entry{}, false
+	}
+	return *p.cur, true
+}
+
 func (p *predicate2Trigger) Active() bool {
 	if p.last == nil {
 		return false
@@ -52,3 +91,123 @@ func (p *predicate2Trigger) String() string {
 	}
 	return p.p(*p.last, *p.cur)
 }
+
+// A memo caches a trigger's message until its next observation,
+// so that evaluating Active and then String does the work once.
+type memo struct {
+	msg   string
+	valid bool
+}
+
+func (m *memo) reset() {
+	m.valid = false
+}
+
+func (m *memo) get(f func() string) string {
+	if !m.valid {
+		m.msg, m.valid = f(), true
+	}
+	return m.msg
+}
+
+type windowTrigger struct {
+	p   func([]dex.Entry) string
+	win *dex.Ring
+	memo
+}
+
+// Window returns a trigger that evaluates p over the last n
+// observed entries, oldest first. It is inactive until n entries
+// have been observed.
+func Window(n int, p func([]dex.Entry) string) Trigger {
+	return &windowTrigger{p: p, win: dex.NewRing(n)}
+}
+
+func (w *windowTrigger) Observe(e dex.Entry) error {
+	w.win.Add(e)
+	w.reset()
+	return nil
+}
+
+func (w *windowTrigger) ObserveBatch(entries []dex.Entry) error {
+	if n := w.win.Cap(); len(entries) > n {
+		entries = entries[len(entries)-n:]
+	}
+	for _, e := range entries {
+		w.win.Add(e)
+	}
+	w.reset()
+	return nil
+}
+
+func (w *windowTrigger) Current() (dex.Entry, bool) {
+	win := w.win.Slice()
+	if len(win) == 0 {
+		return dex.Entry{}, false
+	}
+	return win[len(win)-1], true
+}
+
+func (w *windowTrigger) Active() bool {
+	return w.String() != ""
+}
+
+func (w *windowTrigger) String() string {
+	return w.get(func() string {
+		if w.win.Len() < w.win.Cap() {
+			return ""
+		}
+		return w.p(w.win.Slice())
+	})
+}
+
+type spanTrigger struct {
+	p   func(*stats.Running) string
+	win *stats.Running
+	memo
+}
+
+// Span returns a trigger that evaluates p over the entries observed
+// within d of the latest one, oldest first.
+func Span(d time.Duration, p func([]dex.Entry) string) Trigger {
+	return running(stats.NewRunning(d), func(r *stats.Running) string {
+		return p(r.Entries())
+	})
+}
+
+// A trigger that evaluates p over win, once it holds an entry, so
+// that p may use its incremental statistics.
+func running(win *stats.Running, p func(*stats.Running) string) Trigger {
+	return &spanTrigger{p: p, win: win}
+}
+
+func (s *spanTrigger) Observe(e dex.Entry) error {
+	s.win.Add(e)
+	s.reset()
+	return nil
+}
+
+func (s *spanTrigger) ObserveBatch(entries []dex.Entry) error {
+	for _, e := range entries {
+		s.win.Add(e)
+	}
+	s.reset()
+	return nil
+}
+
+func (s *spanTrigger) Current() (dex.Entry, bool) {
+	return s.win.Last()
+}
+
+func (s *spanTrigger) Active() bool {
+	return s.String() != ""
+}
+
+func (s *spanTrigger) String() string {
+	return s.get(func() string {
+		if s.win.Len() == 0 {
+			return ""
+		}
+		return s.p(s.win)
+	})
+}
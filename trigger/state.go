@@ -0,0 +1,429 @@
+package trigger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"basal.io/x/dex"
+)
+
+// A Stater is a trigger whose state can be saved and restored, so
+// that a monitor may resume where it left off after a restart. Its
+// methods concern only the trigger's own state, not that of the
+// triggers it is composed of; Snapshot and Restore handle those.
+type Stater interface {
+	MarshalState() ([]byte, error)
+	UnmarshalState(b []byte) error
+}
+
+// The version of the encoding produced by Snapshot. It is to be
+// incremented, and older versions handled by Restore, when the state
+// of any trigger changes incompatibly.
+const stateVersion = 1
+
+type snapshot struct {
+	Version int       `json:"version"`
+	Root    stateNode `json:"root"`
+}
+
+type stateNode struct {
+	State    json.RawMessage `json:"state,omitempty"`
+	Children []stateNode     `json:"children,omitempty"`
+}
+
+// Snapshot encodes the state of t and every trigger it is composed
+// of, for Restore. Triggers that are not Staters are taken to have
+// no state of their own. Entries are encoded as by
+// dex.Entry.MarshalJSON, so a restored trigger's entries lack their
+// Raw JSON.
+func Snapshot(t Trigger) ([]byte, error) {
+	root, err := snapshotNode(t)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(snapshot{Version: stateVersion, Root: root})
+}
+
+func snapshotNode(t Trigger) (stateNode, error) {
+	var node stateNode
+	if s, ok := t.(Stater); ok {
+		b, err := s.MarshalState()
+		if err != nil {
+			return node, err
+		}
+		node.State = b
+	}
+	if p, ok := t.(Parent); ok {
+		for _, c := range p.Children() {
+			child, err := snapshotNode(c)
+			if err != nil {
+				return node, err
+			}
+			node.Children = append(node.Children, child)
+		}
+	}
+	return node, nil
+}
+
+// Restore the state of t, and every trigger it is composed of, from
+// b, encoded by Snapshot from a trigger built the same way. Restore
+// fails if the shapes of the triggers differ.
+func Restore(t Trigger, b []byte) error {
+	var snap snapshot
+	if err := json.Unmarshal(b, &snap); err != nil {
+		return err
+	}
+	if snap.Version != stateVersion {
+		return errors.New(fmt.Sprintf("Unsupported trigger state version %d", snap.Version))
+	}
+	return restoreNode(t, snap.Root)
+}
+
+func restoreNode(t Trigger, node stateNode) error {
+	s, ok := t.(Stater)
+	if ok != (node.State != nil) {
+		return errors.New(fmt.Sprintf("Trigger state does not match %T", t))
+	}
+	if ok {
+		if err := s.UnmarshalState(node.State); err != nil {
+			return err
+		}
+	}
+
+	var children []Trigger
+	if p, ok := t.(Parent); ok {
+		children = p.Children()
+	}
+	if len(children) != len(node.Children) {
+		return errors.New(fmt.Sprintf("Trigger state has %d children, %T %d",
+			len(node.Children), t, len(children)))
+	}
+	for i := range children {
+		if err := restoreNode(children[i], node.Children[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type predicateState struct {
+	Last *dex.Entry `json:"last,omitempty"`
+	Cur  *dex.Entry `json:"cur,omitempty"`
+}
+
+func (p *predicateTrigger) MarshalState() ([]byte, error) {
+	return json.Marshal(predicateState{Cur: p.cur})
+}
+
+func (p *predicateTrigger) UnmarshalState(b []byte) error {
+	var st predicateState
+	if err := json.Unmarshal(b, &st); err != nil {
+		return err
+	}
+	p.cur = st.Cur
+	return nil
+}
+
+func (p *predicate2Trigger) MarshalState() ([]byte, error) {
+	return json.Marshal(predicateState{Last: p.last, Cur: p.cur})
+}
+
+func (p *predicate2Trigger) UnmarshalState(b []byte) error {
+	var st predicateState
+	if err := json.Unmarshal(b, &st); err != nil {
+		return err
+	}
+	p.last, p.cur = st.Last, st.Cur
+	return nil
+}
+
+func (w *windowTrigger) MarshalState() ([]byte, error) {
+	return json.Marshal(w.win.Slice())
+}
+
+func (w *windowTrigger) UnmarshalState(b []byte) error {
+	var entries []dex.Entry
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return err
+	}
+	w.win = dex.NewRing(w.win.Cap())
+	return w.ObserveBatch(entries)
+}
+
+func (s *spanTrigger) MarshalState() ([]byte, error) {
+	return json.Marshal(s.win.Entries())
+}
+
+func (s *spanTrigger) UnmarshalState(b []byte) error {
+	var entries []dex.Entry
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return err
+	}
+	s.win.Reset()
+	return s.ObserveBatch(entries)
+}
+
+type plateauState struct {
+	Window     []dex.Entry `json:"window"`
+	Moving     bool        `json:"moving"`
+	Prior      float64     `json:"prior"`
+	Cur        float64     `json:"cur"`
+	Transition bool        `json:"transition"`
+}
+
+func (p *plateauTrigger) MarshalState() ([]byte, error) {
+	return json.Marshal(plateauState{
+		Window:     p.win.Entries(),
+		Moving:     p.moving,
+		Prior:      p.prior,
+		Cur:        p.cur,
+		Transition: p.transition,
+	})
+}
+
+func (p *plateauTrigger) UnmarshalState(b []byte) error {
+	var st plateauState
+	if err := json.Unmarshal(b, &st); err != nil {
+		return err
+	}
+	p.win.Reset()
+	for _, e := range st.Window {
+		p.win.Add(e)
+	}
+	p.moving, p.prior, p.cur, p.transition = st.Moving, st.Prior, st.Cur, st.Transition
+	return nil
+}
+
+type reversalState struct {
+	Window   []dex.Entry `json:"window"`
+	Prior    float64     `json:"prior"`
+	Cur      float64     `json:"cur"`
+	Reversed bool        `json:"reversed"`
+}
+
+func (r *reversalTrigger) MarshalState() ([]byte, error) {
+	return json.Marshal(reversalState{
+		Window:   r.win.Entries(),
+		Prior:    r.prior,
+		Cur:      r.cur,
+		Reversed: r.reversed,
+	})
+}
+
+func (r *reversalTrigger) UnmarshalState(b []byte) error {
+	var st reversalState
+	if err := json.Unmarshal(b, &st); err != nil {
+		return err
+	}
+	r.win.Reset()
+	for _, e := range st.Window {
+		r.win.Add(e)
+	}
+	r.prior, r.cur, r.reversed = st.Prior, st.Cur, st.Reversed
+	return nil
+}
+
+type remindState struct {
+	Last   time.Time  `json:"last"`
+	Cur    *dex.Entry `json:"cur,omitempty"`
+	Active bool       `json:"active"`
+}
+
+func (r *remindTrigger) MarshalState() ([]byte, error) {
+	return json.Marshal(remindState{Last: r.last, Cur: r.cur, Active: r.active})
+}
+
+func (r *remindTrigger) UnmarshalState(b []byte) error {
+	var st remindState
+	if err := json.Unmarshal(b, &st); err != nil {
+		return err
+	}
+	r.last, r.cur, r.active = st.Last, st.Cur, st.Active
+	return nil
+}
+
+func (f *freshTrigger) MarshalState() ([]byte, error) {
+	return json.Marshal(predicateState{Cur: f.cur})
+}
+
+func (f *freshTrigger) UnmarshalState(b []byte) error {
+	var st predicateState
+	if err := json.Unmarshal(b, &st); err != nil {
+		return err
+	}
+	f.cur = st.Cur
+	return nil
+}
+
+type reboundState struct {
+	Low *dex.Entry `json:"low,omitempty"`
+	Cur *dex.Entry `json:"cur,omitempty"`
+}
+
+func (r *reboundTrigger) MarshalState() ([]byte, error) {
+	st := reboundState{Low: r.low}
+	if !r.cur.Time.IsZero() {
+		st.Cur = &r.cur
+	}
+	return json.Marshal(st)
+}
+
+func (r *reboundTrigger) UnmarshalState(b []byte) error {
+	var st reboundState
+	if err := json.Unmarshal(b, &st); err != nil {
+		return err
+	}
+	r.low, r.cur = st.Low, dex.Entry{}
+	if st.Cur != nil {
+		r.cur = *st.Cur
+	}
+	return nil
+}
+
+type coincideState struct {
+	LastA time.Time `json:"lastA"`
+	LastB time.Time `json:"lastB"`
+	Cur   time.Time `json:"cur"`
+}
+
+func (c *coincideTrigger) MarshalState() ([]byte, error) {
+	return json.Marshal(coincideState{LastA: c.lastA, LastB: c.lastB, Cur: c.cur})
+}
+
+func (c *coincideTrigger) UnmarshalState(b []byte) error {
+	var st coincideState
+	if err := json.Unmarshal(b, &st); err != nil {
+		return err
+	}
+	c.lastA, c.lastB, c.cur = st.LastA, st.LastB, st.Cur
+	return nil
+}
+
+type latchState struct {
+	Last   string    `json:"last,omitempty"`
+	Time   time.Time `json:"time"`
+	Count  int       `json:"count,omitempty"`
+	Active bool      `json:"active"`
+}
+
+func (d *distinctTrigger) MarshalState() ([]byte, error) {
+	return json.Marshal(latchState{Last: d.last, Active: d.active})
+}
+
+func (d *distinctTrigger) UnmarshalState(b []byte) error {
+	var st latchState
+	if err := json.Unmarshal(b, &st); err != nil {
+		return err
+	}
+	d.last, d.active = st.Last, st.Active
+	return nil
+}
+
+type clearedState struct {
+	Last   string `json:"last,omitempty"`
+	Was    bool   `json:"was"`
+	Armed  bool   `json:"armed"`
+	Active bool   `json:"active"`
+}
+
+func (c *clearedTrigger) MarshalState() ([]byte, error) {
+	return json.Marshal(clearedState{Last: c.last, Was: c.was, Armed: c.armed, Active: c.active})
+}
+
+func (c *clearedTrigger) UnmarshalState(b []byte) error {
+	var st clearedState
+	if err := json.Unmarshal(b, &st); err != nil {
+		return err
+	}
+	c.last, c.was, c.armed, c.active = st.Last, st.Was, st.Armed, st.Active
+	return nil
+}
+
+func (c *countedTrigger) MarshalState() ([]byte, error) {
+	return json.Marshal(latchState{Active: c.active})
+}
+
+func (c *countedTrigger) UnmarshalState(b []byte) error {
+	var st latchState
+	if err := json.Unmarshal(b, &st); err != nil {
+		return err
+	}
+	c.active = st.Active
+	return nil
+}
+
+func (tt *transitionTrigger) MarshalState() ([]byte, error) {
+	return json.Marshal(latchState{Active: tt.active})
+}
+
+func (tt *transitionTrigger) UnmarshalState(b []byte) error {
+	var st latchState
+	if err := json.Unmarshal(b, &st); err != nil {
+		return err
+	}
+	tt.active = st.Active
+	return nil
+}
+
+func (a *armedTrigger) MarshalState() ([]byte, error) {
+	return json.Marshal(latchState{Count: a.seen})
+}
+
+func (a *armedTrigger) UnmarshalState(b []byte) error {
+	var st latchState
+	if err := json.Unmarshal(b, &st); err != nil {
+		return err
+	}
+	a.seen = st.Count
+	return nil
+}
+
+type recentState struct {
+	History []bool `json:"history"`
+	Active  bool   `json:"active"`
+}
+
+func (r *recentTrigger) MarshalState() ([]byte, error) {
+	return json.Marshal(recentState{History: r.hist, Active: r.active})
+}
+
+func (r *recentTrigger) UnmarshalState(b []byte) error {
+	var st recentState
+	if err := json.Unmarshal(b, &st); err != nil {
+		return err
+	}
+	if n := cap(r.hist); len(st.History) > n {
+		st.History = st.History[len(st.History)-n:]
+	}
+	r.hist = append(r.hist[:0], st.History...)
+	r.active = st.Active
+	return nil
+}
+
+func (r *rateLimitTrigger) MarshalState() ([]byte, error) {
+	return json.Marshal(latchState{Time: r.last, Active: r.active})
+}
+
+func (r *rateLimitTrigger) UnmarshalState(b []byte) error {
+	var st latchState
+	if err := json.Unmarshal(b, &st); err != nil {
+		return err
+	}
+	r.last, r.active = st.Time, st.Active
+	return nil
+}
+
+func (q *quietTrigger) MarshalState() ([]byte, error) {
+	return json.Marshal(latchState{Active: q.quiet})
+}
+
+func (q *quietTrigger) UnmarshalState(b []byte) error {
+	var st latchState
+	if err := json.Unmarshal(b, &st); err != nil {
+		return err
+	}
+	q.quiet = st.Active
+	return nil
+}
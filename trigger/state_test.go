@@ -0,0 +1,71 @@
+package trigger
+
+import (
+	"testing"
+	"time"
+
+	"basal.io/x/dex"
+)
+
+// A tree of stateful triggers, built the same way each time.
+func stateTree() Trigger {
+	return Any(
+		RecentAndNow(3, 3, Below(70)),
+		Distinct(Armed(2, Swing(3, 40))),
+		All(Above(150), Span(time.Hour, func(es []dex.Entry) string {
+			if len(es) >= 4 {
+				return "sustained"
+			}
+			return ""
+		})),
+		Rebound(70, time.Hour, 30),
+		Cleared(Below(60)),
+	)
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	entries := series(start, 100, 65, 62, 60, 58, 90, 120, 160, 170, 180, 185, 140)
+	for i := range entries {
+		entries[i].Skew = time.Duration(i) * time.Second
+	}
+
+	// Snapshot midway through a sustained low: two of the three
+	// lows RecentAndNow awaits have been observed.
+	orig := stateTree()
+	for _, e := range entries[:3] {
+		orig.Observe(e)
+	}
+	b, err := Snapshot(orig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := stateTree()
+	if err := Restore(restored, b); err != nil {
+		t.Fatal(err)
+	}
+	if e, ok := Current(restored); !ok || e.Skew != entries[2].Skew {
+		t.Errorf("restored current entry %+v, want skew %v", e, entries[2].Skew)
+	}
+
+	for i, e := range entries[3:] {
+		orig.Observe(e)
+		restored.Observe(e)
+		if got, want := Explain(restored), Explain(orig); got != want {
+			t.Errorf("at %v: restored %s, want %s", e.Time.Format("15:04"), got, want)
+		}
+		if i == 0 && !restored.Active() {
+			t.Error("restored trigger missed the third low")
+		}
+	}
+}
+
+func TestRestoreMismatch(t *testing.T) {
+	b, err := Snapshot(stateTree())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Restore(Any(Below(70)), b); err == nil {
+		t.Error("restored the state of a different tree")
+	}
+}
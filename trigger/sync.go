@@ -0,0 +1,66 @@
+package trigger
+
+import (
+	"sync"
+
+	"basal.io/x/dex"
+)
+
+type syncTrigger struct {
+	mu sync.Mutex
+	t  Trigger
+}
+
+// Synchronized returns a trigger that behaves as t, but may be used
+// from several goroutines at once: as when a status endpoint asks
+// for its Current entry, or Explain, while Monitor observes it.
+// Triggers are otherwise to be used by one goroutine at a time.
+// Walk and Snapshot reach t itself, without the lock, so should
+// not be used while the trigger is observed.
+func Synchronized(t Trigger) Trigger {
+	return &syncTrigger{t: t}
+}
+
+func (s *syncTrigger) Observe(e dex.Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.t.Observe(e)
+}
+
+func (s *syncTrigger) ObserveBatch(entries []dex.Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return ObserveAll(s.t, entries)
+}
+
+func (s *syncTrigger) TryObserve(e dex.Entry) (changed, active bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return TryObserve(s.t, e)
+}
+
+func (s *syncTrigger) Active() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.t.Active()
+}
+
+func (s *syncTrigger) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.t.String()
+}
+
+func (s *syncTrigger) Current() (dex.Entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return Current(s.t)
+}
+
+func (s *syncTrigger) Explain() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return Explain(s.t)
+}
+
+func (s *syncTrigger) Children() []Trigger { return []Trigger{s.t} }
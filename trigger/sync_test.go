@@ -0,0 +1,51 @@
+package trigger
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"basal.io/x/dex"
+)
+
+func TestSynchronized(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	values := make([]int, 500)
+	for i := range values {
+		values[i] = 50 + i%100
+	}
+	src := series(start, values...)
+	trig := Synchronized(Any(Distinct(Below(70)), Cleared(Below(70))))
+
+	// Query the trigger, as a status endpoint might, while Monitor
+	// observes it.
+	stop := make(chan struct{})
+	queried := make(chan int)
+	go func() {
+		n := 0
+		defer func() { queried <- n }()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			Current(trig)
+			Explain(trig)
+			n++
+		}
+	}()
+	var fired int
+	if err := Backtest(context.Background(), src, trig, time.Time{}, func(dex.Entry, string) { fired++ }); err != nil {
+		t.Fatal(err)
+	}
+	close(stop)
+	<-queried
+
+	if e, ok := Current(trig); !ok || !e.Time.Equal(src[len(src)-1].Time) {
+		t.Errorf("current entry is %v, %v; want the last", e, ok)
+	}
+	if fired == 0 {
+		t.Error("never fired")
+	}
+}
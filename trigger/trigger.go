@@ -23,6 +23,9 @@ func All(trigger ...Trigger) Trigger {
 	return allTrigger(trigger)
 }
 
+// Composites observe every entry with every child, even those whose
+// state cannot change the result, to keep the children's state
+// current; only Active stops at the first child that decides it.
 func (a anyTrigger) Observe(e dex.Entry) error {
 	var errs errs
 	for _, t := range a {
@@ -62,11 +65,12 @@ func (a allTrigger) Observe(e dex.Entry) error {
 }
 
 func (a allTrigger) Active() bool {
-	active := true
 	for _, t := range a {
-		active = active && t.Active()
+		if !t.Active() {
+			return false
+		}
 	}
-	return active
+	return true
 }
 
 func (a allTrigger) String() string {
@@ -79,3 +83,201 @@ func (a allTrigger) String() string {
 	list := strings.Join(strs, ",")
 	return fmt.Sprintf("All(%s)", list)
 }
+
+// An Explainer describes the state of each of its parts.
+type Explainer interface {
+	Explain() string
+}
+
+// Explain describes why t is or is not active: composite triggers
+// list the state of every child, active or not, while other
+// triggers give their state and message.
+func Explain(t Trigger) string {
+	if x, ok := t.(Explainer); ok {
+		return x.Explain()
+	}
+	if t.Active() {
+		return "active: " + t.String()
+	}
+	return "inactive"
+}
+
+func (a anyTrigger) Explain() string {
+	return explain("Any", a.Active(), a)
+}
+
+func (a allTrigger) Explain() string {
+	return explain("All", a.Active(), a)
+}
+
+func explain(name string, active bool, children []Trigger) string {
+	strs := make([]string, len(children))
+	for i := range children {
+		strs[i] = Explain(children[i])
+	}
+	state := "inactive"
+	if active {
+		state = "active"
+	}
+	return fmt.Sprintf("%s %s(%s)", name, state, strings.Join(strs, ", "))
+}
+
+// A BatchObserver observes a batch of entries, in order, more
+// efficiently than one at a time. It is useful for replaying
+// history; the trigger is evaluated once, after the batch.
+type BatchObserver interface {
+	ObserveBatch(entries []dex.Entry) error
+}
+
+// ObserveAll makes t observe each of entries, in order, as a batch
+// if t is a BatchObserver.
+func ObserveAll(t Trigger, entries []dex.Entry) error {
+	if b, ok := t.(BatchObserver); ok {
+		return b.ObserveBatch(entries)
+	}
+	for _, e := range entries {
+		if err := t.Observe(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a anyTrigger) ObserveBatch(entries []dex.Entry) error {
+	var errs errs
+	for _, t := range a {
+		errs.record(ObserveAll(t, entries))
+	}
+
+	return errs.err()
+}
+
+func (a allTrigger) ObserveBatch(entries []dex.Entry) error {
+	var errs errs
+	for _, t := range a {
+		errs.record(ObserveAll(t, entries))
+	}
+
+	return errs.err()
+}
+
+// A TryObserver observes an entry and reports, in the same step,
+// whether doing so changed whether it is active, and whether it now
+// is. Triggers that keep their state implement it without asking
+// themselves, or their children, whether they are active.
+type TryObserver interface {
+	TryObserve(e dex.Entry) (changed, active bool, err error)
+}
+
+// TryObserve makes t observe e, returning whether doing so changed
+// whether t is active, and whether it now is. If t is not a
+// TryObserver, TryObserve asks t whether it is active before and
+// after observing e.
+func TryObserve(t Trigger, e dex.Entry) (changed, active bool, err error) {
+	if o, ok := t.(TryObserver); ok {
+		return o.TryObserve(e)
+	}
+	was := t.Active()
+	if err := t.Observe(e); err != nil {
+		return false, was, err
+	}
+	active = t.Active()
+	return active != was, active, nil
+}
+
+// Observe e with t, whose state is kept in *active, as TryObserve.
+func tryObserve(t Trigger, active *bool, e dex.Entry) (changed, now bool, err error) {
+	was := *active
+	err = t.Observe(e)
+	return *active != was, *active, err
+}
+
+// Any and All learn whether each child was active from whether it
+// changed, and so ask none of them.
+func (a anyTrigger) TryObserve(e dex.Entry) (changed, active bool, err error) {
+	var (
+		errs errs
+		was  bool
+	)
+	for _, t := range a {
+		c, act, err := TryObserve(t, e)
+		errs.record(err)
+		was = was || act != c
+		active = active || act
+	}
+	return active != was, active, errs.err()
+}
+
+func (a allTrigger) TryObserve(e dex.Entry) (changed, active bool, err error) {
+	var errs errs
+	was, active := true, true
+	for _, t := range a {
+		c, act, err := TryObserve(t, e)
+		errs.record(err)
+		was = was && act != c
+		active = active && act
+	}
+	return active != was, active, errs.err()
+}
+
+// A Currenter reports the latest entry a trigger has observed. Like
+// a trigger's other methods, Current is not safe to call while the
+// trigger observes an entry on another goroutine; query a trigger
+// wrapped by Synchronized instead.
+type Currenter interface {
+	Current() (dex.Entry, bool)
+}
+
+// Current returns the latest entry observed by t, if t is a
+// Currenter, and whether there is one.
+func Current(t Trigger) (dex.Entry, bool) {
+	if c, ok := t.(Currenter); ok {
+		return c.Current()
+	}
+	return dex.Entry{}, false
+}
+
+func (a anyTrigger) Current() (dex.Entry, bool) {
+	return latest(a)
+}
+
+func (a allTrigger) Current() (dex.Entry, bool) {
+	return latest(a)
+}
+
+// Latest returns the latest entry observed by any of ts.
+func latest(ts []Trigger) (dex.Entry, bool) {
+	var (
+		cur   dex.Entry
+		found bool
+	)
+	for _, t := range ts {
+		if e, ok := Current(t); ok && (!found || e.Time.After(cur.Time)) {
+			cur, found = e, true
+		}
+	}
+	return cur, found
+}
+
+// A Parent is a trigger composed of other triggers.
+type Parent interface {
+	Children() []Trigger
+}
+
+// Walk calls fn for t and, depth first, each trigger it is composed
+// of, with its depth below t.
+func Walk(t Trigger, fn func(t Trigger, depth int)) {
+	walk(t, 0, fn)
+}
+
+func walk(t Trigger, depth int, fn func(Trigger, int)) {
+	fn(t, depth)
+	if p, ok := t.(Parent); ok {
+		for _, c := range p.Children() {
+			walk(c, depth+1, fn)
+		}
+	}
+}
+
+func (a anyTrigger) Children() []Trigger { return a }
+func (a allTrigger) Children() []Trigger { return a }
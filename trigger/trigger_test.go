@@ -0,0 +1,214 @@
+package trigger
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"basal.io/x/dex"
+)
+
+// A naiveWindow is a Window that evaluates its predicate on every
+// call of Active and String.
+type naiveWindow struct {
+	p   func([]dex.Entry) string
+	win *dex.Ring
+}
+
+func (w *naiveWindow) Observe(e dex.Entry) error {
+	w.win.Add(e)
+	return nil
+}
+
+func (w *naiveWindow) Active() bool {
+	return w.String() != ""
+}
+
+func (w *naiveWindow) String() string {
+	if w.win.Len() < w.win.Cap() {
+		return ""
+	}
+	return w.p(w.win.Slice())
+}
+
+// A naiveAny is an Any that asks every child whether it is active.
+type naiveAny []Trigger
+
+func (a naiveAny) Observe(e dex.Entry) error {
+	for _, t := range a {
+		t.Observe(e)
+	}
+	return nil
+}
+
+func (a naiveAny) Active() bool {
+	active := false
+	for _, t := range a {
+		if t.Active() {
+			active = true
+		}
+	}
+	return active
+}
+
+func (a naiveAny) String() string {
+	var strs []string
+	for _, t := range a {
+		if t.Active() {
+			strs = append(strs, t.String())
+		}
+	}
+	return fmt.Sprintf("Any(%s)", strings.Join(strs, ","))
+}
+
+// A predicate that is true if the mean of its entries is above hi.
+func meanAbove(hi int) func([]dex.Entry) string {
+	return func(entries []dex.Entry) string {
+		sum := 0
+		for _, e := range entries {
+			sum += e.Value
+		}
+		if mean := sum / len(entries); mean > hi {
+			return fmt.Sprintf("mean %d > %d", mean, hi)
+		}
+		return ""
+	}
+}
+
+// Observe a day of entries with n windowed triggers, under Any,
+// evaluating the result after each entry as Monitor does.
+func benchmarkAnyWindows(b *testing.B, n int, naive bool) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	entries := make([]dex.Entry, 24*12)
+	for i := range entries {
+		entries[i] = dex.Entry{Time: start.Add(time.Duration(i) * 5 * time.Minute), Value: 100 + i%200}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		children := make([]Trigger, n)
+		for j := range children {
+			p := meanAbove(120 + 10*j)
+			if naive {
+				children[j] = &naiveWindow{p: p, win: dex.NewRing(12)}
+			} else {
+				children[j] = Window(12, p)
+			}
+		}
+		var t Trigger = Any(children...)
+		if naive {
+			t = naiveAny(children)
+		}
+		for _, e := range entries {
+			if _, active, _ := TryObserve(t, e); active {
+				_ = t.String()
+			}
+		}
+	}
+}
+
+func BenchmarkAnyWindows(b *testing.B) {
+	for _, n := range []int{4, 16, 64} {
+		b.Run(fmt.Sprintf("n=%d/short-circuit", n), func(b *testing.B) { benchmarkAnyWindows(b, n, false) })
+		b.Run(fmt.Sprintf("n=%d/naive", n), func(b *testing.B) { benchmarkAnyWindows(b, n, true) })
+	}
+}
+
+func TestWalk(t *testing.T) {
+	tree := Any(
+		Below(70),
+		All(Above(180), Distinct(Arrow(dex.DoubleUp))),
+		Coincide(time.Hour, Below(55), Delta(3)),
+	)
+	var got []string
+	Walk(tree, func(t Trigger, depth int) {
+		got = append(got, fmt.Sprintf("%d %T", depth, t))
+	})
+	want := []string{
+		"0 trigger.anyTrigger",
+		"1 *trigger.predicateTrigger",
+		"1 trigger.allTrigger",
+		"2 *trigger.predicateTrigger",
+		"2 *trigger.distinctTrigger",
+		"3 *trigger.predicateTrigger",
+		"1 *trigger.coincideTrigger",
+		"2 *trigger.predicateTrigger",
+		"2 *trigger.predicate2Trigger",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("walked\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestExplainAll(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	a := All(Below(150), Any(Above(200), Below(110)), Above(120))
+	for _, e := range series(start, 100, 130) {
+		a.Observe(e)
+	}
+	// Below(110) has cleared, so Any, and with it All, is inactive,
+	// though the other children of All are active.
+	want := "All inactive(active: 130 < 150, Any inactive(inactive, inactive), active: 130 > 120)"
+	if got := Explain(a); got != want {
+		t.Errorf("Explain() = %q, want %q", got, want)
+	}
+	if a.Active() {
+		t.Errorf("All is active: %s", a)
+	}
+}
+
+func TestTryObserve(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	entries := series(start, 100, 65, 60, 60, 90, 190, 200, 150, 60, 55, 120)
+	for _, c := range []struct {
+		name string
+		new  func() Trigger
+	}{
+		{"Below", func() Trigger { return Below(70) }},
+		{"Any", func() Trigger { return Any(Below(70), Above(180)) }},
+		{"All", func() Trigger { return All(Below(100), RecentAndNow(2, 3, Below(70))) }},
+		{"Empty All", func() Trigger { return All() }},
+		{"Distinct", func() Trigger { return Distinct(Delta(-2)) }},
+		{"RecentAndNow", func() Trigger { return RecentAndNow(2, 3, Below(70)) }},
+		{"Cleared", func() Trigger { return Cleared(Below(70)) }},
+		{"Transitions", func() Trigger { tt, _ := Transitions(Above(180)); return tt }},
+		{"Reversal", func() Trigger { return Reversal(1) }},
+		{"Nested", func() Trigger { return Any(All(Below(70), Distinct(Below(62))), Counted("test", Above(180))) }},
+	} {
+		tried, observed := c.new(), c.new()
+		was := tried.Active()
+		for _, e := range entries {
+			changed, active, err := TryObserve(tried, e)
+			if err != nil {
+				t.Fatal(err)
+			}
+			observed.Observe(e)
+			if want := observed.Active(); active != want {
+				t.Errorf("%s at %d: active %v, want %v", c.name, e.Value, active, want)
+			}
+			if active != tried.Active() {
+				t.Errorf("%s at %d: reported active %v, but Active() = %v", c.name, e.Value, active, tried.Active())
+			}
+			if want := active != was; changed != want {
+				t.Errorf("%s at %d: changed %v, want %v", c.name, e.Value, changed, want)
+			}
+			was = active
+		}
+	}
+}
+
+// The stateful triggers and composites report changes themselves.
+var (
+	_ TryObserver = anyTrigger(nil)
+	_ TryObserver = allTrigger(nil)
+	_ TryObserver = (*distinctTrigger)(nil)
+	_ TryObserver = (*clearedTrigger)(nil)
+	_ TryObserver = (*transitionTrigger)(nil)
+	_ TryObserver = (*recentTrigger)(nil)
+	_ TryObserver = (*rateLimitTrigger)(nil)
+	_ TryObserver = (*remindTrigger)(nil)
+	_ TryObserver = (*plateauTrigger)(nil)
+	_ TryObserver = (*reversalTrigger)(nil)
+	_ TryObserver = (*countedTrigger)(nil)
+)
@@ -0,0 +1,158 @@
+package trigger
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"basal.io/x/dex"
+	"basal.io/x/stats"
+)
+
+// OutOfRangePct fires when more than pct percent of the entries
+// within window of the latest are outside [lo, hi].
+func OutOfRangePct(window time.Duration, pct float64, lo, hi int) Trigger {
+	return running(stats.NewRunning(window, stats.WithRange(lo, hi)), func(r *stats.Running) string {
+		out := r.Len() - r.InRange()
+		cur := 100 * float64(out) / float64(r.Len())
+		if cur > pct {
+			return fmt.Sprintf("OutOfRange(%.0f%% > %.0f%%)", cur, pct)
+		} else {
+			return ""
+		}
+	})
+}
+
+// RelativeDrop fires when the latest value is more than pct percent
+// below the median of the entries within window of it.
+func RelativeDrop(window time.Duration, pct float64) Trigger {
+	return Span(window, func(es []dex.Entry) string {
+		cur := es[len(es)-1].Value
+		base := stats.Percentile(es, 0.5)
+		if float64(cur) < float64(base)*(1-pct/100) {
+			return fmt.Sprintf("RelativeDrop(%d < %d - %.0f%%)", cur, base, pct)
+		} else {
+			return ""
+		}
+	})
+}
+
+// AUCAbove fires when the area under the curve of values above hi,
+// over the entries within window of the latest, exceeds limit in
+// mg/dL·min: a measure of the burden of high glucose. See
+// stats.AUCAbove.
+func AUCAbove(window time.Duration, hi int, limit float64) Trigger {
+	return running(stats.NewRunning(window, stats.WithAUCAbove(hi)), func(r *stats.Running) string {
+		auc := r.AUCAbove()
+		if auc > limit {
+			return fmt.Sprintf("AUCAbove(%d: %.0f > %.0f mg/dL·min)", hi, auc, limit)
+		} else {
+			return ""
+		}
+	})
+}
+
+type plateauTrigger struct {
+	win      *stats.Running
+	maxSlope float64
+
+	moving     bool    // Whether the slope last exceeded maxSlope.
+	prior, cur float64 // The slopes before and at the latest entry.
+	transition bool
+}
+
+// Plateau fires when the slope fitted to the entries within window of
+// the latest falls within ±maxSlope mg/dL/min, having exceeded it on
+// the previous entry: that is, when glucose stops rising or falling.
+func Plateau(window time.Duration, maxSlope float64) Trigger {
+	return &plateauTrigger{win: stats.NewRunning(window), maxSlope: maxSlope}
+}
+
+func (p *plateauTrigger) Observe(e dex.Entry) error {
+	p.win.Add(e)
+	p.transition = false
+
+	slope, ok := p.win.Slope()
+	if !ok {
+		return nil
+	}
+	p.prior, p.cur = p.cur, slope
+	flat := math.Abs(slope) <= p.maxSlope
+	p.transition = flat && p.moving
+	p.moving = !flat
+	return nil
+}
+
+func (p *plateauTrigger) Active() bool {
+	return p.transition
+}
+
+func (p *plateauTrigger) TryObserve(e dex.Entry) (changed, active bool, err error) {
+	return tryObserve(p, &p.transition, e)
+}
+
+func (p *plateauTrigger) String() string {
+	if !p.transition {
+		return ""
+	}
+	return fmt.Sprintf("Plateau(%+.1f -> %+.1f mg/dL/min)", p.prior, p.cur)
+}
+
+func (p *plateauTrigger) Current() (dex.Entry, bool) {
+	return p.win.Last()
+}
+
+// The span of entries over which Reversal fits slopes.
+const reversalWindow = 15 * time.Minute
+
+type reversalTrigger struct {
+	win      *stats.Running
+	minSlope float64
+
+	prior, cur float64 // The last slopes exceeding minSlope, in magnitude.
+	reversed   bool
+}
+
+// Reversal fires when the slope fitted to the entries within 15
+// minutes of the latest exceeds minSlope mg/dL/min in magnitude, and
+// in the opposite direction to the last slope that did: that is, at
+// a peak or trough, as when glucose rising fast turns to falling.
+// Flatter slopes between them are ignored as noise.
+func Reversal(minSlope float64) Trigger {
+	return &reversalTrigger{win: stats.NewRunning(reversalWindow), minSlope: minSlope}
+}
+
+func (r *reversalTrigger) Observe(e dex.Entry) error {
+	r.win.Add(e)
+	r.reversed = false
+
+	slope, ok := r.win.Slope()
+	if !ok || math.Abs(slope) <= r.minSlope {
+		return nil
+	}
+	if r.cur != 0 && (slope > 0) != (r.cur > 0) {
+		r.reversed = true
+		r.prior = r.cur
+	}
+	r.cur = slope
+	return nil
+}
+
+func (r *reversalTrigger) Active() bool {
+	return r.reversed
+}
+
+func (r *reversalTrigger) TryObserve(e dex.Entry) (changed, active bool, err error) {
+	return tryObserve(r, &r.reversed, e)
+}
+
+func (r *reversalTrigger) String() string {
+	if !r.reversed {
+		return ""
+	}
+	return fmt.Sprintf("Reversal(%+.1f -> %+.1f mg/dL/min)", r.prior, r.cur)
+}
+
+func (r *reversalTrigger) Current() (dex.Entry, bool) {
+	return r.win.Last()
+}
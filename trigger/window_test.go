@@ -0,0 +1,41 @@
+package trigger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRunningTriggers(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		name   string
+		trig   Trigger
+		values []int
+		want   string
+	}{
+		{"OutOfRangePct", OutOfRangePct(time.Hour, 50, 70, 180), []int{100, 200, 60, 100}, ""},
+		{"OutOfRangePct", OutOfRangePct(time.Hour, 50, 70, 180), []int{100, 200, 60, 190}, "OutOfRange(75% > 50%)"},
+		// The first entries fall out of the window.
+		{"OutOfRangePct", OutOfRangePct(10*time.Minute, 50, 70, 180), []int{200, 200, 100, 100, 100}, ""},
+		{"RelativeDrop", RelativeDrop(time.Hour, 20), []int{150, 150, 150, 100}, "RelativeDrop(100 < 150 - 20%)"},
+		{"RelativeDrop", RelativeDrop(time.Hour, 20), []int{150, 150, 150, 120}, ""},
+		// The median is robust to the outlier, where the mean is not.
+		{"RelativeDrop", RelativeDrop(time.Hour, 20), []int{150, 150, 40, 150, 100}, "RelativeDrop(100 < 150 - 20%)"},
+		{"AUCAbove", AUCAbove(time.Hour, 180, 100), []int{180, 200, 200}, "AUCAbove(180: 150 > 100 mg/dL·min)"},
+		{"AUCAbove", AUCAbove(time.Hour, 180, 100), []int{180, 200, 180}, ""},
+		{"Swing", Swing(3, 50), []int{100, 160, 140}, "Swing(100..160: 60 > 50)"},
+		{"Swing", Swing(3, 50), []int{100, 160, 140, 130}, ""},
+		{"Swing", Swing(3, 50), []int{100, 160}, ""},
+		{"Flatline", Flatline(3), []int{90, 100, 100, 100}, "Flatline(100 x 3)"},
+		{"Flatline", Flatline(3), []int{100, 100}, ""},
+		{"Flatline", Flatline(3), []int{100, 100, 101}, ""},
+	} {
+		src := series(start, c.values...)
+		for _, e := range src {
+			c.trig.Observe(e)
+		}
+		if got := c.trig.String(); got != c.want {
+			t.Errorf("%s of %v: got %q, want %q", c.name, c.values, got, c.want)
+		}
+	}
+}
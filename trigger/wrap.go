@@ -0,0 +1,436 @@
+package trigger
+
+import (
+	"fmt"
+	"time"
+
+	"basal.io/x/dex"
+)
+
+type distinctTrigger struct {
+	t      Trigger
+	last   string
+	active bool
+}
+
+// Distinct returns a trigger that is active when t is, but only for
+// the first of consecutive entries on which t gives the same
+// message.
+func Distinct(t Trigger) Trigger {
+	return &distinctTrigger{t: t}
+}
+
+func (d *distinctTrigger) Observe(e dex.Entry) error {
+	if err := d.t.Observe(e); err != nil {
+		return err
+	}
+	if !d.t.Active() {
+		d.last, d.active = "", false
+		return nil
+	}
+	msg := d.t.String()
+	d.active = msg != d.last
+	d.last = msg
+	return nil
+}
+
+func (d *distinctTrigger) Current() (dex.Entry, bool) {
+	return Current(d.t)
+}
+
+func (d *distinctTrigger) Active() bool {
+	return d.active
+}
+
+func (d *distinctTrigger) TryObserve(e dex.Entry) (changed, active bool, err error) {
+	return tryObserve(d, &d.active, e)
+}
+
+func (d *distinctTrigger) String() string {
+	if !d.active {
+		return ""
+	}
+	return d.last
+}
+
+type clearedTrigger struct {
+	t, ok  Trigger
+	last   string // The message of t while it was last active.
+	was    bool   // Whether t was active on the previous entry.
+	armed  bool   // Whether t has gone inactive, and ok is awaited.
+	active bool
+}
+
+// Cleared returns a trigger that is active on the entry on which t
+// becomes inactive, having been active, as when a low resolves.
+func Cleared(t Trigger) Trigger {
+	return ClearedWhen(t, nil)
+}
+
+// ClearedWhen is like Cleared, but once t becomes inactive, awaits
+// an entry on which ok is also active, such as Above(80) for a
+// value comfortably back in range. If t becomes active again first,
+// nothing is cleared. A nil ok is always active.
+func ClearedWhen(t, ok Trigger) Trigger {
+	return &clearedTrigger{t: t, ok: ok}
+}
+
+func (c *clearedTrigger) Observe(e dex.Entry) error {
+	var errs errs
+	errs.record(c.t.Observe(e))
+	if c.ok != nil {
+		errs.record(c.ok.Observe(e))
+	}
+
+	c.active = false
+	if c.t.Active() {
+		c.last, c.was, c.armed = c.t.String(), true, false
+		return errs.err()
+	}
+	if c.was {
+		c.was, c.armed = false, true
+	}
+	if c.armed && (c.ok == nil || c.ok.Active()) {
+		c.active, c.armed = true, false
+	}
+	return errs.err()
+}
+
+func (c *clearedTrigger) Current() (dex.Entry, bool) {
+	return Current(c.t)
+}
+
+func (c *clearedTrigger) Active() bool {
+	return c.active
+}
+
+func (c *clearedTrigger) TryObserve(e dex.Entry) (changed, active bool, err error) {
+	return tryObserve(c, &c.active, e)
+}
+
+func (c *clearedTrigger) String() string {
+	if !c.active {
+		return ""
+	}
+	return fmt.Sprintf("recovered from %s", c.last)
+}
+
+// A TransitionEvent reports that a trigger became active or
+// inactive on observing an entry.
+type TransitionEvent struct {
+	Active bool      // The trigger's new state.
+	Entry  dex.Entry // The entry observed.
+	Msg    string    // The trigger's message, if active.
+}
+
+// The capacity of channels returned by Transitions.
+const transitionBuffer = 16
+
+type transitionTrigger struct {
+	t      Trigger
+	events chan TransitionEvent
+	block  bool
+	active bool
+}
+
+// Transitions returns a trigger that behaves as t, and a channel on
+// which it sends an event whenever its state changes. The channel is
+// buffered; events that do not fit are dropped.
+func Transitions(t Trigger) (Trigger, <-chan TransitionEvent) {
+	return transitions(t, transitionBuffer, false)
+}
+
+// BlockingTransitions is like Transitions, but the channel has the
+// given capacity and Observe blocks until events fit.
+func BlockingTransitions(t Trigger, size int) (Trigger, <-chan TransitionEvent) {
+	return transitions(t, size, true)
+}
+
+func transitions(t Trigger, size int, block bool) (Trigger, <-chan TransitionEvent) {
+	tt := &transitionTrigger{t: t, events: make(chan TransitionEvent, size), block: block}
+	return tt, tt.events
+}
+
+func (tt *transitionTrigger) Observe(e dex.Entry) error {
+	if err := tt.t.Observe(e); err != nil {
+		return err
+	}
+	active := tt.t.Active()
+	if active == tt.active {
+		return nil
+	}
+	tt.active = active
+
+	ev := TransitionEvent{Active: active, Entry: e}
+	if active {
+		ev.Msg = tt.t.String()
+	}
+	if tt.block {
+		tt.events <- ev
+		return nil
+	}
+	select {
+	case tt.events <- ev:
+	default:
+	}
+	return nil
+}
+
+func (tt *transitionTrigger) Current() (dex.Entry, bool) {
+	return Current(tt.t)
+}
+
+func (tt *transitionTrigger) Active() bool {
+	return tt.t.Active()
+}
+
+func (tt *transitionTrigger) TryObserve(e dex.Entry) (changed, active bool, err error) {
+	return tryObserve(tt, &tt.active, e)
+}
+
+func (tt *transitionTrigger) String() string {
+	return tt.t.String()
+}
+
+type armedTrigger struct {
+	t    Trigger
+	n    int
+	seen int
+}
+
+// Armed returns a trigger that behaves as t once t has observed n
+// readings, and is inactive until then. Entries that are not
+// valid, and synthetic ones, are not counted.
+func Armed(n int, t Trigger) Trigger {
+	return &armedTrigger{t: t, n: n}
+}
+
+func (a *armedTrigger) Observe(e dex.Entry) error {
+	if e.Valid() == nil && !e.Synthetic && a.seen < a.n {
+		a.seen++
+	}
+	return a.t.Observe(e)
+}
+
+func (a *armedTrigger) Current() (dex.Entry, bool) {
+	return Current(a.t)
+}
+
+func (a *armedTrigger) Active() bool {
+	return a.seen >= a.n && a.t.Active()
+}
+
+func (a *armedTrigger) String() string {
+	if a.seen < a.n {
+		return ""
+	}
+	return a.t.String()
+}
+
+type recentTrigger struct {
+	t      Trigger
+	k      int
+	hist   []bool // Whether t was active on each of the last n entries, oldest first.
+	active bool
+}
+
+// RecentAndNow returns a trigger that is active when t is active on
+// the current entry and on at least k of the last n entries,
+// counting the current one: RecentAndNow(2, 3, Below(70)) ignores
+// a single low reading, yet does not wait for a sustained low once
+// two of three are low. A trigger active on k of n entries but not
+// the current one is not active: the condition must persist.
+func RecentAndNow(k, n int, t Trigger) Trigger {
+	if n < 1 {
+		n = 1
+	}
+	return &recentTrigger{t: t, k: k, hist: make([]bool, 0, n)}
+}
+
+func (r *recentTrigger) Observe(e dex.Entry) error {
+	if err := r.t.Observe(e); err != nil {
+		return err
+	}
+	now := r.t.Active()
+	if len(r.hist) == cap(r.hist) {
+		r.hist = append(r.hist[:0], r.hist[1:]...)
+	}
+	r.hist = append(r.hist, now)
+
+	count := 0
+	for _, a := range r.hist {
+		if a {
+			count++
+		}
+	}
+	r.active = now && count >= r.k
+	return nil
+}
+
+func (r *recentTrigger) Current() (dex.Entry, bool) {
+	return Current(r.t)
+}
+
+func (r *recentTrigger) Active() bool {
+	return r.active
+}
+
+func (r *recentTrigger) TryObserve(e dex.Entry) (changed, active bool, err error) {
+	return tryObserve(r, &r.active, e)
+}
+
+func (r *recentTrigger) String() string {
+	if !r.active {
+		return ""
+	}
+	return r.t.String()
+}
+
+type rateLimitTrigger struct {
+	t      Trigger
+	d      time.Duration
+	now    Clock
+	last   time.Time
+	active bool
+}
+
+// RateLimitActivations returns a trigger that is active when t is,
+// but at most once per d of wall time, as told by now (nil means
+// time.Now), whatever the times of the entries.
+func RateLimitActivations(d time.Duration, now Clock, t Trigger) Trigger {
+	return &rateLimitTrigger{t: t, d: d, now: now}
+}
+
+func (r *rateLimitTrigger) Observe(e dex.Entry) error {
+	if err := r.t.Observe(e); err != nil {
+		return err
+	}
+	r.active = false
+	if r.t.Active() {
+		now := r.now.now()
+		if r.last.IsZero() || now.Sub(r.last) >= r.d {
+			r.active = true
+			r.last = now
+		}
+	}
+	return nil
+}
+
+func (r *rateLimitTrigger) Current() (dex.Entry, bool) {
+	return Current(r.t)
+}
+
+func (r *rateLimitTrigger) Active() bool {
+	return r.active
+}
+
+func (r *rateLimitTrigger) TryObserve(e dex.Entry) (changed, active bool, err error) {
+	return tryObserve(r, &r.active, e)
+}
+
+func (r *rateLimitTrigger) String() string {
+	if !r.active {
+		return ""
+	}
+	return r.t.String()
+}
+
+type quietTrigger struct {
+	start, end     int
+	loc            *time.Location
+	severe, normal Trigger
+	quiet          bool
+}
+
+// QuietHours returns a trigger that behaves as severe for entries
+// from hour start until hour end, in loc (nil means local time), and
+// as normal otherwise. The quiet hours may wrap past midnight, as
+// from 22 until 7. Both triggers observe every entry.
+func QuietHours(start, end int, loc *time.Location, severe Trigger, normal Trigger) Trigger {
+	if loc == nil {
+		loc = time.Local
+	}
+	return &quietTrigger{start: start, end: end, loc: loc, severe: severe, normal: normal}
+}
+
+func (q *quietTrigger) Observe(e dex.Entry) error {
+	var errs errs
+	errs.record(q.severe.Observe(e))
+	errs.record(q.normal.Observe(e))
+
+	h := e.Time.In(q.loc).Hour()
+	if q.start <= q.end {
+		q.quiet = q.start <= h && h < q.end
+	} else {
+		q.quiet = h >= q.start || h < q.end
+	}
+	return errs.err()
+}
+
+func (q *quietTrigger) current() Trigger {
+	if q.quiet {
+		return q.severe
+	}
+	return q.normal
+}
+
+func (q *quietTrigger) Current() (dex.Entry, bool) {
+	return Current(q.current())
+}
+
+func (q *quietTrigger) Active() bool {
+	return q.current().Active()
+}
+
+func (q *quietTrigger) String() string {
+	if !q.Active() {
+		return ""
+	}
+	if q.quiet {
+		return fmt.Sprintf("Quiet(%s)", q.severe.String())
+	}
+	return q.normal.String()
+}
+
+type measuredTrigger struct {
+	Trigger
+}
+
+// Measured returns a trigger that behaves as t, but observes only
+// measured readings, skipping synthetic entries such as those added
+// by dex.Interpolate. Wrapping two-point triggers such as Delta
+// keeps them computing rates between real readings.
+func Measured(t Trigger) Trigger {
+	return measuredTrigger{t}
+}
+
+func (m measuredTrigger) Observe(e dex.Entry) error {
+	if e.Synthetic {
+		return nil
+	}
+	return m.Trigger.Observe(e)
+}
+
+func (m measuredTrigger) Current() (dex.Entry, bool) {
+	return Current(m.Trigger)
+}
+
+func (d *distinctTrigger) Children() []Trigger    { return []Trigger{d.t} }
+func (c *clearedTrigger) Children() []Trigger     { return children(c.t, c.ok) }
+func (tt *transitionTrigger) Children() []Trigger { return []Trigger{tt.t} }
+func (a *armedTrigger) Children() []Trigger       { return []Trigger{a.t} }
+func (r *recentTrigger) Children() []Trigger      { return []Trigger{r.t} }
+func (r *rateLimitTrigger) Children() []Trigger   { return []Trigger{r.t} }
+func (q *quietTrigger) Children() []Trigger       { return []Trigger{q.severe, q.normal} }
+func (m measuredTrigger) Children() []Trigger     { return []Trigger{m.Trigger} }
+
+// Children returns those of ts that are not nil.
+func children(ts ...Trigger) []Trigger {
+	var out []Trigger
+	for _, t := range ts {
+		if t != nil {
+			out = append(out, t)
+		}
+	}
+	return out
+}
@@ -0,0 +1,121 @@
+package trigger
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"basal.io/x/dex"
+)
+
+func TestRecentAndNow(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		k, n   int
+		values []int
+		want   bool
+	}{
+		{2, 3, []int{60}, false},
+		{2, 3, []int{60, 60}, true},
+		{2, 3, []int{60, 100, 60}, true},
+		// The first low has left the window of the last three.
+		{2, 3, []int{60, 100, 100, 60}, false},
+		// The condition must hold now.
+		{2, 3, []int{60, 60, 100}, false},
+		{3, 3, []int{60, 60}, false},
+		{3, 3, []int{100, 60, 60, 60}, true},
+		{1, 1, []int{60}, true},
+		{1, 0, []int{100, 60}, true},
+		{0, 3, []int{100}, false},
+	} {
+		r := RecentAndNow(c.k, c.n, Below(70))
+		for _, e := range series(start, c.values...) {
+			r.Observe(e)
+		}
+		if got := r.Active(); got != c.want {
+			t.Errorf("%d of %d, %v: Active() = %v, want %v", c.k, c.n, c.values, got, c.want)
+		}
+		if got, want := r.String() != "", c.want; got != want {
+			t.Errorf("%d of %d, %v: String() = %q", c.k, c.n, c.values, r.String())
+		}
+	}
+}
+
+// The message of tr after observing each of entries, or "-" where it
+// is inactive.
+func messages(t *testing.T, tr Trigger, entries []dex.Entry) []string {
+	t.Helper()
+	msgs := make([]string, len(entries))
+	for i, e := range entries {
+		tr.Observe(e)
+		msgs[i] = "-"
+		if tr.Active() {
+			msgs[i] = tr.String()
+		} else if s := tr.String(); s != "" {
+			t.Errorf("inactive at %d, but String() = %q", e.Value, s)
+		}
+	}
+	return msgs
+}
+
+func TestDistinct(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	// Below stays active throughout the low, its message changing
+	// with the value.
+	d := Distinct(Below(70))
+	got := messages(t, d, series(start, 100, 65, 65, 62, 62, 62, 65, 100, 65))
+	want := []string{"-", "65 < 70", "-", "62 < 70", "-", "-", "65 < 70", "-", "65 < 70"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestArmed(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	// Below(70) is active on every entry, but Armed is silent until
+	// it has seen three valid readings.
+	entries := series(start, 60, 0, 60, 60, 60, 60, 60)
+	entries[3].Synthetic = true
+	entries[4].Dir = dex.RateOutOfRange
+	got := messages(t, Armed(3, Below(70)), entries)
+	want := []string{"-", "-", "-", "-", "-", "60 < 70", "60 < 70"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	for n := 0; n <= 1; n++ {
+		if got := messages(t, Armed(n, Below(70)), series(start, 60)); got[0] != "60 < 70" {
+			t.Errorf("Armed(%d) is silent on the first reading", n)
+		}
+	}
+}
+
+func TestCleared(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		name   string
+		t      Trigger
+		values []int
+		want   []string
+	}{
+		{
+			"Cleared",
+			Cleared(Below(70)),
+			[]int{100, 60, 65, 90, 95, 60, 100, 60},
+			[]string{"-", "-", "-", "recovered from 65 < 70", "-", "-", "recovered from 60 < 70", "-"},
+		},
+		{
+			// The low returns at 65 before the value is back above
+			// 80, so only its own recovery is reported.
+			"ClearedWhen",
+			ClearedWhen(Below(70), Above(80)),
+			[]int{100, 60, 75, 65, 75, 85, 90, 60, 85},
+			[]string{"-", "-", "-", "-", "-", "recovered from 65 < 70", "-", "-", "recovered from 60 < 70"},
+		},
+	} {
+		got := messages(t, c.t, series(start, c.values...))
+		if strings.Join(got, ",") != strings.Join(c.want, ",") {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+		}
+	}
+}